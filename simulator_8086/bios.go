@@ -0,0 +1,253 @@
+package simulator8086
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	SectorSize        = 512
+	BootSectorAddress = 0x7c00
+	bootSignature     = 0xaa55
+)
+
+// status codes of INT 13h, returned in AH
+const (
+	diskStatusOK             = 0x00
+	diskStatusInvalidCommand = 0x01
+	diskStatusNotFound       = 0x04
+	diskStatusTimeout        = 0x80
+)
+
+type DiskGeometry struct {
+	Cylinders       int
+	Heads           int
+	SectorsPerTrack int
+	// drive type reported by INT 13h function 08h
+	DriveType byte
+}
+
+func (g DiskGeometry) Sectors() int {
+	return g.Cylinders * g.Heads * g.SectorsPerTrack
+}
+
+var floppyGeometries = []DiskGeometry{
+	{Cylinders: 40, Heads: 2, SectorsPerTrack: 9, DriveType: 1},
+	{Cylinders: 80, Heads: 2, SectorsPerTrack: 9, DriveType: 3},
+	{Cylinders: 80, Heads: 2, SectorsPerTrack: 15, DriveType: 2},
+	{Cylinders: 80, Heads: 2, SectorsPerTrack: 18, DriveType: 4},
+	{Cylinders: 80, Heads: 2, SectorsPerTrack: 36, DriveType: 6},
+}
+
+type DiskStorage interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Disk is a floppy disk backed by a raw image. Images that do not match the size of a
+// standard floppy use the geometry of the next larger one, the missing sectors read as zeros.
+type Disk struct {
+	Storage  DiskStorage
+	Geometry DiskGeometry
+	size     int64
+}
+
+func NewDisk(storage DiskStorage, size int64) (*Disk, error) {
+	for _, geometry := range floppyGeometries {
+		if size <= int64(geometry.Sectors()*SectorSize) {
+			return &Disk{Storage: storage, Geometry: geometry, size: size}, nil
+		}
+	}
+	return nil, fmt.Errorf("disk image is %d bytes large, larger than any supported floppy", size)
+}
+
+// OpenDiskImage opens an image file for reading and writing, Close closes the file
+func OpenDiskImage(path string) (*Disk, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	disk, err := NewDisk(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return disk, nil
+}
+
+func (d *Disk) Close() error {
+	closer, ok := d.Storage.(io.Closer)
+	if !ok {
+		return nil
+	}
+	return closer.Close()
+}
+
+func (d *Disk) ReadSector(lba int, buffer []byte) error {
+	for i := range buffer {
+		buffer[i] = 0
+	}
+	_, err := d.Storage.ReadAt(buffer[:SectorSize], int64(lba*SectorSize))
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (d *Disk) WriteSector(lba int, buffer []byte) error {
+	_, err := d.Storage.WriteAt(buffer[:SectorSize], int64(lba*SectorSize))
+	return err
+}
+
+// LBA converts a cylinder, head and sector address to a logical block address, sectors start at 1
+func (d *Disk) LBA(cylinder int, head int, sector int) (int, bool) {
+	geometry := d.Geometry
+	if cylinder >= geometry.Cylinders || head >= geometry.Heads || sector < 1 || sector > geometry.SectorsPerTrack {
+		return 0, false
+	}
+	return (cylinder*geometry.Heads+head)*geometry.SectorsPerTrack + sector - 1, true
+}
+
+// LoadBootSector creates a context with the first sector of the disk at 0000:7C00, like the BIOS does before booting
+func LoadBootSector(disk *Disk) (*Context, error) {
+	sector := make([]byte, SectorSize)
+	err := disk.ReadSector(0, sector)
+	if err != nil {
+		return nil, err
+	}
+	signature := uint16(sector[SectorSize-2]) | uint16(sector[SectorSize-1])<<8
+	if signature != bootSignature {
+		return nil, fmt.Errorf("boot sector signature is 0x%04x, expected 0x%04x", signature, bootSignature)
+	}
+
+	context := &Context{}
+	for i, b := range sector {
+		context.WriteMemory8(BootSectorAddress+i, b)
+	}
+	for _, register := range []RegisterName{CS, DS, ES, SS} {
+		context.SetRegister(register, 0)
+	}
+	context.SetRegister(SP, BootSectorAddress)
+	// the drive the system was booted from
+	context.SetRegister(DL, 0)
+	context.InstructionPointer = BootSectorAddress
+	context.SetFlag(Flag_Interrupt, true)
+	return context, nil
+}
+
+// BIOS implements disk services for drive 0 through INT 13h and teletype output through INT 10h
+type BIOS struct {
+	Disk   *Disk
+	Stdout io.Writer
+
+	diskStatus byte
+}
+
+func (b *BIOS) Install(context *Context) {
+	context.InterruptHandlers[0x10] = b.videoInterrupt
+	context.InterruptHandlers[0x13] = b.diskInterrupt
+}
+
+func (b *BIOS) videoInterrupt(context *Context, number byte) error {
+	function := byte(context.GetRegister(AH))
+	switch function {
+	case 0x00:
+		// setting the video mode has no effect on the headless output
+	case 0x0e:
+		_, err := b.Stdout.Write([]byte{byte(context.GetRegister(AL))})
+		return err
+	default:
+		return fmt.Errorf("video function %02xh is not implemented", function)
+	}
+	return nil
+}
+
+func (b *BIOS) setDiskStatus(context *Context, status byte) {
+	b.diskStatus = status
+	context.SetRegister(AH, int16(status))
+	context.SetFlag(Flag_Carry, status != diskStatusOK)
+}
+
+func (b *BIOS) diskInterrupt(context *Context, number byte) error {
+	function := byte(context.GetRegister(AH))
+	if b.Disk == nil || byte(context.GetRegister(DL)) != 0 {
+		b.setDiskStatus(context, diskStatusTimeout)
+		return nil
+	}
+
+	switch function {
+	case 0x00:
+		b.setDiskStatus(context, diskStatusOK)
+	case 0x01:
+		// the status of the last operation is returned in AH, then it is reset
+		b.setDiskStatus(context, b.diskStatus)
+		b.diskStatus = diskStatusOK
+	case 0x02, 0x03:
+		return b.transferSectors(context, function == 0x03)
+	case 0x08:
+		geometry := b.Disk.Geometry
+		maxCylinder := geometry.Cylinders - 1
+		context.SetRegister(AX, 0)
+		context.SetRegister(BL, int16(geometry.DriveType))
+		context.SetRegister(CH, int16(maxCylinder&0xff))
+		context.SetRegister(CL, int16(geometry.SectorsPerTrack|(maxCylinder>>8)<<6))
+		context.SetRegister(DH, int16(geometry.Heads-1))
+		context.SetRegister(DL, 1)
+		// there is no diskette parameter table
+		context.SetRegister(ES, 0)
+		context.SetRegister(DI, 0)
+		b.setDiskStatus(context, diskStatusOK)
+	default:
+		b.setDiskStatus(context, diskStatusInvalidCommand)
+	}
+	return nil
+}
+
+// transferSectors reads AL sectors starting at the CHS address in CX and DH to or from ES:BX
+func (b *BIOS) transferSectors(context *Context, write bool) error {
+	count := int(byte(context.GetRegister(AL)))
+	cx := int(uint16(context.GetRegister(CX)))
+	cylinder := cx>>8 | (cx&0xc0)<<2
+	sector := cx & 0x3f
+	head := int(byte(context.GetRegister(DH)))
+
+	lba, ok := b.Disk.LBA(cylinder, head, sector)
+	if !ok || count == 0 || lba+count > b.Disk.Geometry.Sectors() {
+		context.SetRegister(AL, 0)
+		b.setDiskStatus(context, diskStatusNotFound)
+		return nil
+	}
+
+	address := PhysicalAddress(context.GetRegister(ES), context.GetRegister(BX))
+	buffer := make([]byte, SectorSize)
+	for i := 0; i < count; i++ {
+		if write {
+			for j := range buffer {
+				buffer[j] = byte(context.readData(context.GetRegister(ES), address+i*SectorSize+j, false))
+			}
+			err := b.Disk.WriteSector(lba+i, buffer)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := b.Disk.ReadSector(lba+i, buffer)
+			if err != nil {
+				return err
+			}
+			for j, value := range buffer {
+				context.writeData(context.GetRegister(ES), address+i*SectorSize+j, uint16(value), false)
+			}
+		}
+	}
+
+	context.SetRegister(AL, int16(count))
+	b.setDiskStatus(context, diskStatusOK)
+	return nil
+}
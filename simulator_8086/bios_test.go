@@ -0,0 +1,124 @@
+package simulator8086
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootFromDiskImage(t *testing.T) {
+	image := make([]byte, 4*SectorSize)
+	copy(image, []byte{
+		0xb8, 0x01, 0x02, // mov ax, 0201h
+		0xb9, 0x02, 0x00, // mov cx, 0002h
+		0xba, 0x00, 0x00, // mov dx, 0
+		0xbb, 0x00, 0x7e, // mov bx, 7e00h
+		0xcd, 0x13, // int 13h
+		0xa0, 0x00, 0x7e, // mov al, [7e00h]
+		0xb4, 0x0e, // mov ah, 0eh
+		0xcd, 0x10, // int 10h
+		0xb8, 0x01, 0x03, // mov ax, 0301h
+		0xb9, 0x03, 0x00, // mov cx, 0003h
+		0xcd, 0x13, // int 13h
+		0xf4, // hlt
+	})
+	image[510], image[511] = 0x55, 0xaa
+	copy(image[SectorSize:], "second sector")
+
+	path := filepath.Join(t.TempDir(), "floppy.img")
+	require.NoError(t, os.WriteFile(path, image, 0o644))
+	disk, err := OpenDiskImage(path)
+	require.NoError(t, err)
+	require.Equal(t, 720, disk.Geometry.Sectors())
+
+	context, err := LoadBootSector(disk)
+	require.NoError(t, err)
+	require.Equal(t, int16(BootSectorAddress), context.InstructionPointer)
+
+	stdout := &bytes.Buffer{}
+	bios := &BIOS{Disk: disk, Stdout: stdout}
+	bios.Install(context)
+	runUntilHalted(t, context)
+	require.NoError(t, disk.Close())
+
+	require.Equal(t, "s", stdout.String())
+	require.False(t, context.GetFlag(Flag_Carry))
+	require.Equal(t, int16(1), context.GetRegister(AL))
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("second sector"), written[2*SectorSize:2*SectorSize+13])
+}
+
+func TestDiskServiceErrors(t *testing.T) {
+	disk, err := NewDisk(&memoryDisk{}, 360*1024)
+	require.NoError(t, err)
+	require.Equal(t, 40, disk.Geometry.Cylinders)
+	_, err = NewDisk(&memoryDisk{}, 10*1024*1024)
+	require.Error(t, err)
+
+	context := &Context{}
+	bios := &BIOS{Disk: disk}
+	bios.Install(context)
+
+	// drive parameters
+	context.SetRegister(AH, 0x08)
+	require.NoError(t, context.Interrupt(0x13))
+	require.False(t, context.GetFlag(Flag_Carry))
+	require.Equal(t, int16(39), context.GetRegister(CH))
+	require.Equal(t, int16(9), context.GetRegister(CL))
+	require.Equal(t, int16(1), context.GetRegister(DH))
+
+	// sector 10 does not exist on a 9 sector track
+	context.SetRegister(AX, 0x0201)
+	context.SetRegister(CX, 0x000a)
+	context.SetRegister(DX, 0)
+	require.NoError(t, context.Interrupt(0x13))
+	require.True(t, context.GetFlag(Flag_Carry))
+	require.Equal(t, int16(diskStatusNotFound), context.GetRegister(AH))
+
+	context.SetRegister(AH, 0x01)
+	require.NoError(t, context.Interrupt(0x13))
+	require.True(t, context.GetFlag(Flag_Carry))
+	require.Equal(t, int16(diskStatusNotFound), context.GetRegister(AH))
+
+	// reading the status resets it
+	context.SetRegister(AH, 0x01)
+	require.NoError(t, context.Interrupt(0x13))
+	require.False(t, context.GetFlag(Flag_Carry))
+	require.Equal(t, int16(diskStatusOK), context.GetRegister(AH))
+
+	// only drive 0 is present
+	context.SetRegister(AH, 0x00)
+	context.SetRegister(DL, 0x80)
+	require.NoError(t, context.Interrupt(0x13))
+	require.True(t, context.GetFlag(Flag_Carry))
+
+	_, err = LoadBootSector(disk)
+	require.Error(t, err)
+}
+
+type memoryDisk struct {
+	data []byte
+}
+
+func (d *memoryDisk) ReadAt(buffer []byte, offset int64) (int, error) {
+	if offset >= int64(len(d.data)) {
+		return 0, io.EOF
+	}
+	n := copy(buffer, d.data[offset:])
+	if n < len(buffer) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (d *memoryDisk) WriteAt(buffer []byte, offset int64) (int, error) {
+	for int64(len(d.data)) < offset+int64(len(buffer)) {
+		d.data = append(d.data, 0)
+	}
+	return copy(d.data[offset:], buffer), nil
+}
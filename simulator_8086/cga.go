@@ -0,0 +1,146 @@
+package simulator8086
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	CGATextAddress = 0xb8000
+	CGAMemorySize  = 0x4000
+	CGAColumns     = 80
+	CGARows        = 25
+)
+
+// code page 437, the character set of the CGA character ROM
+var cp437 = []rune("" +
+	" ☺☻♥♦♣♠•◘○◙♂♀♪♫☼►◄↕‼¶§▬↨↑↓→←∟↔▲▼" +
+	" !\"#$%&'()*+,-./0123456789:;<=>?" +
+	"@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_" +
+	"`abcdefghijklmnopqrstuvwxyz{|}~⌂" +
+	"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+	"áíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+	"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ ")
+
+// CGA colors are ordered blue, green, red while ANSI colors are ordered red, green, blue
+var cgaToANSIColor = []int{0, 4, 2, 6, 1, 5, 3, 7}
+
+// CGATextDisplay is the video memory of a CGA adapter in 80x25 text mode.
+// Every character cell consists of the character followed by its attribute byte.
+type CGATextDisplay struct {
+	Buffer [CGAMemorySize]byte
+}
+
+func NewCGATextDisplay() *CGATextDisplay {
+	return &CGATextDisplay{}
+}
+
+// Attach maps the display at B800:0000, a memory map is created if the context does not have one yet
+func (d *CGATextDisplay) Attach(context *Context) error {
+	if context.MemoryMap == nil {
+		context.MemoryMap = NewMemoryMap()
+	}
+	return context.MemoryMap.MapDevice(CGATextAddress, CGAMemorySize, d)
+}
+
+func (d *CGATextDisplay) ReadMemory(offset int) byte {
+	return d.Buffer[offset]
+}
+
+func (d *CGATextDisplay) WriteMemory(offset int, value byte) {
+	d.Buffer[offset] = value
+}
+
+func (d *CGATextDisplay) DeviceName() string {
+	return "cga"
+}
+
+func (d *CGATextDisplay) SaveState() ([]byte, error) {
+	return append([]byte{}, d.Buffer[:]...), nil
+}
+
+func (d *CGATextDisplay) LoadState(state []byte) error {
+	if len(state) != len(d.Buffer) {
+		return errors.New("invalid size of video memory")
+	}
+	copy(d.Buffer[:], state)
+	return nil
+}
+
+func (d *CGATextDisplay) Character(row int, column int) (byte, byte) {
+	offset := (row*CGAColumns + column) * 2
+	return d.Buffer[offset], d.Buffer[offset+1]
+}
+
+// Text returns the screen content without colors, trailing spaces of every line are removed
+func (d *CGATextDisplay) Text() string {
+	result := strings.Builder{}
+	for row := 0; row < CGARows; row++ {
+		line := make([]rune, CGAColumns)
+		for column := 0; column < CGAColumns; column++ {
+			character, _ := d.Character(row, column)
+			line[column] = cp437[character]
+		}
+		result.WriteString(strings.TrimRight(string(line), " "))
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+func ansiColorSequence(attribute byte) string {
+	foreground := attribute & 0b1111
+	background := (attribute >> 4) & 0b111
+	foregroundCode := 30 + cgaToANSIColor[foreground&0b111]
+	if foreground&0b1000 != 0 {
+		foregroundCode += 60
+	}
+	return fmt.Sprintf("\x1b[%d;%dm", foregroundCode, 40+cgaToANSIColor[background])
+}
+
+// Render draws the screen at the top left of the terminal using ANSI escape sequences
+func (d *CGATextDisplay) Render(writer io.Writer) error {
+	result := strings.Builder{}
+	result.WriteString("\x1b[H")
+	for row := 0; row < CGARows; row++ {
+		lastAttribute := -1
+		for column := 0; column < CGAColumns; column++ {
+			character, attribute := d.Character(row, column)
+			if int(attribute) != lastAttribute {
+				result.WriteString(ansiColorSequence(attribute))
+				lastAttribute = int(attribute)
+			}
+			result.WriteRune(cp437[character])
+		}
+		result.WriteString("\x1b[0m\n")
+	}
+
+	_, err := io.WriteString(writer, result.String())
+	return err
+}
+
+// CGARenderer renders the display after every Interval instructions, an Interval of 0 or less renders after each one
+type CGARenderer struct {
+	BaseObserver
+	Display  *CGATextDisplay
+	Writer   io.Writer
+	Interval int
+	// Err is the first error that occurred while rendering
+	Err error
+
+	instructionCount int
+}
+
+func (r *CGARenderer) AfterInstruction(context *Context, address int, instruction Instruction) {
+	r.instructionCount++
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	if r.instructionCount%interval != 0 || r.Err != nil {
+		return
+	}
+	r.Err = r.Display.Render(r.Writer)
+}
@@ -0,0 +1,62 @@
+package simulator8086
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCGATextDisplay(t *testing.T) {
+	require.Len(t, cp437, 256)
+
+	context := &Context{}
+	display := NewCGATextDisplay()
+	require.NoError(t, display.Attach(context))
+
+	program := []byte{
+		0xb8, 0x00, 0xb8, // mov ax, 0xb800
+		0x8e, 0xd8, // mov ds, ax
+		0xb8, 0x48, 0x1e, // mov ax, 0x1e48
+		0xa3, 0xa2, 0x00, // mov [162], ax
+		0xb0, 0x69, // mov al, 'i'
+		0xa3, 0xa4, 0x00, // mov [164], ax
+	}
+	copy(context.Memory[:], program)
+
+	output := &bytes.Buffer{}
+	context.AddObserver(&CGARenderer{Display: display, Writer: output, Interval: 5})
+	for i := 0; i < 6; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, "\n Hi\n"+strings.Repeat("\n", CGARows-2), display.Text())
+
+	// rendered once after the fifth instruction, before the 'i' was written
+	require.Equal(t, 1, strings.Count(output.String(), "\x1b[H"))
+	require.Contains(t, output.String(), "\x1b[93;44mH\x1b[30;40m ")
+
+	output.Reset()
+	require.NoError(t, display.Render(output))
+	require.Contains(t, output.String(), "\x1b[93;44mHi\x1b[30;40m ")
+}
+
+func TestCGARendererZeroInterval(t *testing.T) {
+	context := &Context{}
+	display := NewCGATextDisplay()
+	require.NoError(t, display.Attach(context))
+	copy(context.Memory[:], []byte{
+		0xb8, 0x01, 0x00, // mov ax, 1
+		0xbb, 0x02, 0x00, // mov bx, 2
+	})
+
+	output := &bytes.Buffer{}
+	context.AddObserver(&CGARenderer{Display: display, Writer: output})
+	for i := 0; i < 2; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, strings.Count(output.String(), "\x1b[H"))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	simulator8086 "simulator_8086"
+)
+
+func run() error {
+	metadata := flag.String("metadata", "", "metadata file with the flags masks, defaults to 8088.json in the directory if it exists")
+	failures := flag.Int("failures", 3, "number of failing test cases printed per opcode, 0 prints all")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		return fmt.Errorf("usage: cputest [flags] directory")
+	}
+	directory := flag.Arg(0)
+
+	options := simulator8086.CPUTestOptions{MaxFailures: *failures}
+	path := *metadata
+	if path == "" {
+		path = filepath.Join(directory, "8088.json")
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			path = ""
+		}
+	}
+	if path != "" {
+		masks, err := simulator8086.LoadCPUTestMetadata(path)
+		if err != nil {
+			return err
+		}
+		options.FlagsMasks = masks
+	}
+
+	results, err := simulator8086.RunCPUTestDirectory(directory, options)
+	if err != nil {
+		return err
+	}
+	err = simulator8086.WriteCPUTestReport(os.Stdout, results)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		failed += result.Failed
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d test cases failed", failed)
+	}
+	return nil
+}
+
+func main() {
+	err := run()
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", err))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	gocontext "context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	simulator8086 "simulator_8086"
+)
+
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func printRegisters(context *simulator8086.Context) {
+	registers := []simulator8086.RegisterName{
+		simulator8086.AX, simulator8086.BX, simulator8086.CX, simulator8086.DX,
+		simulator8086.SP, simulator8086.BP, simulator8086.SI, simulator8086.DI,
+		simulator8086.CS, simulator8086.DS, simulator8086.ES, simulator8086.SS,
+	}
+	for _, register := range registers {
+		fmt.Printf("%s: 0x%04x\n", register, uint16(context.GetRegister(register)))
+	}
+	fmt.Printf("ip: 0x%04x\n", uint16(context.InstructionPointer))
+	fmt.Printf("flags: 0x%04x\n", context.FlagsWord())
+}
+
+// writeOutput calls write with the file at path, or with stdout if path is '-'
+func writeOutput(path string, write func(writer io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writeHeatmap(path string, heatmap *simulator8086.MemoryHeatmap, blockSize int, width int) error {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return writeOutput(path, func(writer io.Writer) error {
+			return heatmap.WriteCSV(writer, blockSize)
+		})
+	}
+	img, err := heatmap.Image(blockSize, width)
+	if err != nil {
+		return err
+	}
+	return simulator8086.WriteImageFile(img, path)
+}
+
+func run() error {
+	segment := flag.Int("segment", -1, "segment the program or its PSP is loaded at, defaults to 0 for raw binaries and 0x1000 for .com and .exe files")
+	args := flag.String("args", "", "command line arguments passed to DOS programs")
+	gdb := flag.String("gdb", "", "serve the GDB remote protocol on this TCP address, use '-' for stdin/stdout")
+	limit := flag.Uint64("limit", 1000000, "maximum number of instructions to simulate, 0 means no limit")
+	clocks := flag.Uint64("clocks", 0, "maximum number of clocks to simulate, 0 means no limit")
+	icache := flag.Bool("icache", false, "cache decoded instructions by physical address and decode the rest of a basic block on a miss")
+	timer := flag.Bool("timer", false, "attach an 8259 PIC and an 8253 PIT at the ports of the PC, the PIT raises IRQ 0")
+	serial := flag.Bool("serial", false, "attach an 8250 UART at COM1 connected to stdin and stdout, it raises IRQ 4 when -timer is set")
+	record := flag.String("record", "", "record port reads and hardware interrupts to this input log")
+	replay := flag.String("replay", "", "replay port reads and hardware interrupts from this input log")
+	checkpoint := flag.Uint64("checkpoint", 1000, "record a hash of the registers every n instructions, replays compare them to find divergences early, 0 disables it")
+	profile := flag.String("profile", "", "write a profile of the estimated clocks per function and label to this file, use '-' for stdout")
+	profileSort := flag.String("profile-sort", "inclusive", "sort the profile by inclusive or exclusive clocks")
+	coverage := flag.String("coverage", "", "write the disassembly of the program with execution counts and branch outcomes to this file, use '-' for stdout")
+	coverageSummary := flag.String("coverage-summary", "", "write the number of executed instructions and branch outcomes of the program as JSON to this file")
+	heatmapPath := flag.String("heatmap", "", "export the memory reads and writes per block as .png, .ppm or .csv file")
+	heatmapBlock := flag.Int("heatmap-block", 16, "bytes per block of the heatmap")
+	heatmapWidth := flag.Int("heatmap-width", 256, "blocks per line of the heatmap image")
+	memoryStats := flag.Bool("memory-stats", false, "print the working set, stack high-water mark and reads and writes per segment")
+	tracePath := flag.String("trace", "", "write the calls and interrupts as Chrome trace events with simulated clocks as timestamps to this file")
+	sanitize := flag.Bool("sanitize", false, "report reads of undefined memory and registers, returns not matching a call, writes into the program and stack pointers outside of -stack-bottom and -stack-top")
+	sanitizeFault := flag.Bool("sanitize-fault", false, "stop the simulation at the first violation found by -sanitize")
+	stackBottom := flag.Int("stack-bottom", 0, "lowest physical address of the stack checked by -sanitize")
+	stackTop := flag.Int("stack-top", 0, "physical address of the empty stack checked by -sanitize, 0 disables the check")
+	symbols := flag.String("symbols", "", "file of hexadecimal physical addresses and names used for the profile and the trace")
+	cga := flag.Bool("cga", false, "attach a CGA text mode display and render it after the simulation")
+	cgaInterval := flag.Int("cga-interval", 0, "also render the CGA display after every n instructions")
+	imagePath := flag.String("image", "", "export a memory region as .png or .ppm image after the simulation")
+	imageStart := flag.Int("image-start", 0, "physical address of the first pixel of the image")
+	imageWidth := flag.Int("image-width", 64, "width of the image in pixels")
+	imageHeight := flag.Int("image-height", 64, "height of the image in pixels")
+	imageFormat := flag.String("image-format", "rgba8", "pixel format of the image: rgba8, palette8 or cga2bpp")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		return fmt.Errorf("usage: sim8086 [flags] program.bin|program.com|program.exe|floppy.img")
+	}
+
+	format, err := simulator8086.PixelFormatFromName(*imageFormat)
+	if err != nil {
+		return err
+	}
+
+	profileSortBy := simulator8086.PS_Inclusive
+	switch *profileSort {
+	case "inclusive":
+	case "exclusive":
+		profileSortBy = simulator8086.PS_Exclusive
+	default:
+		return fmt.Errorf("unknown profile sort %q, expected inclusive or exclusive", *profileSort)
+	}
+
+	program, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var context *simulator8086.Context
+	var dos *simulator8086.DOS
+	// physical address and size of the loaded code, used for the coverage listing
+	codeStart, codeSize := 0, len(program)
+	switch strings.ToLower(filepath.Ext(flag.Arg(0))) {
+	case ".com", ".exe":
+		if *segment < 0 {
+			*segment = 0x1000
+		}
+		if strings.EqualFold(filepath.Ext(flag.Arg(0)), ".exe") {
+			context, err = simulator8086.LoadEXE(program, uint16(*segment), *args)
+			if err == nil {
+				header, _ := simulator8086.ParseMZHeader(program)
+				codeStart, codeSize = simulator8086.PhysicalAddress(int16(*segment+0x10), 0), header.ImageSize()
+			}
+		} else {
+			context, err = simulator8086.LoadCOM(program, uint16(*segment), *args)
+			codeStart = simulator8086.PhysicalAddress(int16(*segment), 0x100)
+		}
+		if err != nil {
+			return err
+		}
+		dos = &simulator8086.DOS{Stdin: os.Stdin, Stdout: os.Stdout}
+		dos.Install(context)
+	case ".img", ".ima":
+		disk, err := simulator8086.OpenDiskImage(flag.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer disk.Close()
+		context, err = simulator8086.LoadBootSector(disk)
+		if err != nil {
+			return err
+		}
+		codeStart, codeSize = simulator8086.BootSectorAddress, simulator8086.SectorSize
+		bios := &simulator8086.BIOS{Disk: disk, Stdout: os.Stdout}
+		bios.Install(context)
+	default:
+		if *segment < 0 {
+			*segment = 0
+		}
+		context = &simulator8086.Context{}
+		for _, register := range []simulator8086.RegisterName{simulator8086.CS, simulator8086.DS, simulator8086.ES, simulator8086.SS} {
+			context.SetRegister(register, int16(*segment))
+		}
+		codeStart = simulator8086.PhysicalAddress(int16(*segment), 0)
+		copy(context.Memory[codeStart:], program)
+	}
+
+	var pic *simulator8086.PIC
+	if *timer {
+		pic = simulator8086.NewPIC()
+		err = pic.Attach(context)
+		if err != nil {
+			return err
+		}
+		err = simulator8086.NewPIT(pic).Attach(context)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *serial {
+		uart := simulator8086.NewUART(os.Stdin, os.Stdout)
+		uart.PIC = pic
+		err = uart.Attach(context)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *record != "" && *replay != "" {
+		return fmt.Errorf("-record and -replay can not be used together")
+	}
+	if *record != "" {
+		context.InputLog = simulator8086.NewInputRecording(*checkpoint)
+	}
+	if *replay != "" {
+		context.InputLog, err = simulator8086.LoadInputLogFile(*replay)
+		if err != nil {
+			return err
+		}
+	}
+
+	names := map[int]string{}
+	if *symbols != "" {
+		file, err := os.Open(*symbols)
+		if err != nil {
+			return err
+		}
+		names, err = simulator8086.ParseSymbols(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	var profiler *simulator8086.Profiler
+	if *profile != "" {
+		profiler = simulator8086.NewProfiler(names)
+		context.AddObserver(profiler)
+	}
+
+	var tracer *simulator8086.CallTracer
+	if *tracePath != "" {
+		tracer = simulator8086.NewCallTracer(names)
+		context.AddObserver(tracer)
+	}
+
+	var sanitizer *simulator8086.Sanitizer
+	if *sanitize || *sanitizeFault {
+		sanitizer = simulator8086.NewSanitizer(simulator8086.SanitizerOptions{
+			CodeStart:        codeStart,
+			CodeSize:         codeSize,
+			StackBottom:      *stackBottom,
+			StackTop:         *stackTop,
+			FaultOnViolation: *sanitizeFault,
+		})
+		if dos != nil {
+			// the PSP is written by the loader, the word at the top of the stack returns to its int 20h
+			sanitizer.DefineMemory(simulator8086.PhysicalAddress(int16(*segment), 0), 0x100)
+			sanitizer.AllowReturn(simulator8086.PhysicalAddress(context.GetRegister(simulator8086.SS), context.GetRegister(simulator8086.SP)))
+		}
+		context.AddObserver(sanitizer)
+	}
+
+	var codeCoverage *simulator8086.Coverage
+	if *coverage != "" || *coverageSummary != "" {
+		codeCoverage = simulator8086.NewCoverage()
+		context.AddObserver(codeCoverage)
+	}
+
+	var heatmap *simulator8086.MemoryHeatmap
+	if *heatmapPath != "" || *memoryStats {
+		heatmap = simulator8086.NewMemoryHeatmap()
+		context.AddObserver(heatmap)
+	}
+
+	display := simulator8086.NewCGATextDisplay()
+	if *cga {
+		err = display.Attach(context)
+		if err != nil {
+			return err
+		}
+		if *cgaInterval > 0 {
+			context.AddObserver(&simulator8086.CGARenderer{Display: display, Writer: os.Stdout, Interval: *cgaInterval})
+		}
+	}
+
+	if *icache {
+		context.InstructionCache = simulator8086.NewInstructionCache(simulator8086.InstructionCacheOptions{BasicBlocks: true})
+	}
+
+	if *gdb == "-" {
+		return simulator8086.NewGDBServer(context).Serve(stdio{os.Stdin, os.Stdout})
+	}
+	if *gdb != "" {
+		return simulator8086.ListenAndServeGDB(*gdb, context)
+	}
+
+	// Ctrl-C stops the simulation, the state is printed nevertheless
+	ctx, stop := signal.NotifyContext(gocontext.Background(), os.Interrupt)
+	defer stop()
+	result := simulator8086.Run(ctx, context, simulator8086.RunOptions{MaxInstructions: *limit, MaxClocks: *clocks})
+	var simulationErr error
+	if result.Reason == simulator8086.SR_Fault {
+		simulationErr = result.Err
+	}
+
+	if *record != "" {
+		err = simulator8086.SaveInputLogFile(*record, context.InputLog)
+		if err != nil {
+			return err
+		}
+	}
+
+	if profiler != nil {
+		err = writeOutput(*profile, func(writer io.Writer) error {
+			return profiler.WriteReport(writer, profileSortBy)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if tracer != nil {
+		err = writeOutput(*tracePath, func(writer io.Writer) error {
+			return tracer.WriteJSON(writer, context)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *coverage != "" {
+		err = writeOutput(*coverage, func(writer io.Writer) error {
+			return codeCoverage.WriteListing(writer, context, codeStart, codeSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *coverageSummary != "" {
+		err = writeOutput(*coverageSummary, func(writer io.Writer) error {
+			return codeCoverage.WriteSummary(writer, context, codeStart, codeSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *heatmapPath != "" {
+		err = writeHeatmap(*heatmapPath, heatmap, *heatmapBlock, *heatmapWidth)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *imagePath != "" {
+		err = simulator8086.ExportImage(context, simulator8086.ImageOptions{
+			Start:  *imageStart,
+			Width:  *imageWidth,
+			Height: *imageHeight,
+			Format: format,
+		}, *imagePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *cga {
+		err = display.Render(os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+
+	printRegisters(context)
+	if sanitizer != nil {
+		for _, violation := range sanitizer.Violations {
+			fmt.Printf("sanitizer: %s\n", violation.Error())
+		}
+	}
+	if *memoryStats {
+		err = heatmap.WriteStatistics(os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("stopped: %s\n", result)
+	if dos != nil && dos.Terminated {
+		fmt.Printf("exit code: %d\n", dos.ExitCode)
+	}
+	return simulationErr
+}
+
+func main() {
+	err := run()
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", err))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,152 @@
+package simulator8086
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type BranchCoverage struct {
+	Taken    uint64
+	NotTaken uint64
+}
+
+// Coverage counts the executions of every instruction address and the outcomes of the conditional jumps
+type Coverage struct {
+	BaseObserver
+	Counts   map[int]uint64
+	Branches map[int]BranchCoverage
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{
+		Counts:   map[int]uint64{},
+		Branches: map[int]BranchCoverage{},
+	}
+}
+
+func (c *Coverage) AfterInstruction(context *Context, address int, instruction Instruction) {
+	if context.InstructionFailed() {
+		return
+	}
+	c.Counts[address]++
+	if !instruction.Type.IsConditionalJump() {
+		return
+	}
+
+	branch := c.Branches[address]
+	if context.InstructionAddress() != (address+instruction.SizeInBytes)%MemorySize {
+		branch.Taken++
+	} else {
+		branch.NotTaken++
+	}
+	c.Branches[address] = branch
+}
+
+// CoverageSummary compares the executed instructions and branch outcomes of a code region to all of them.
+// Every conditional jump has two outcomes, taken and not taken.
+type CoverageSummary struct {
+	Instructions         int `json:"instructions"`
+	ExecutedInstructions int `json:"executedInstructions"`
+	Branches             int `json:"branches"`
+	ExecutedBranches     int `json:"executedBranches"`
+	BranchOutcomes       int `json:"branchOutcomes"`
+	CoveredOutcomes      int `json:"coveredOutcomes"`
+}
+
+type coverageLine struct {
+	address     int
+	instruction Instruction
+	// false for bytes that do not decode, they are listed as data
+	valid bool
+}
+
+// coverageLines disassembles the code region linearly, like the listing of an assembler
+func coverageLines(context *Context, start int, size int) []coverageLine {
+	// the decoder needs the bytes after the region too, like fetchInstruction
+	code := make([]byte, size+maxInstructionSize)
+	for i := range code {
+		code[i] = context.ReadMemory8((start + i) % MemorySize)
+	}
+
+	lines := make([]coverageLine, 0)
+	for offset := 0; offset < size; {
+		address := (start + offset) % MemorySize
+		instruction, err := DecodeInstruction(code[offset:])
+		if err != nil || offset+instruction.SizeInBytes > size {
+			lines = append(lines, coverageLine{address: address, instruction: Instruction{SizeInBytes: 1}})
+			offset++
+			continue
+		}
+		lines = append(lines, coverageLine{address: address, instruction: instruction, valid: true})
+		offset += instruction.SizeInBytes
+	}
+	return lines
+}
+
+func (c *Coverage) Summary(context *Context, start int, size int) CoverageSummary {
+	summary := CoverageSummary{}
+	for _, line := range coverageLines(context, start, size) {
+		if !line.valid {
+			continue
+		}
+		summary.Instructions++
+		if c.Counts[line.address] != 0 {
+			summary.ExecutedInstructions++
+		}
+		if !line.instruction.Type.IsConditionalJump() {
+			continue
+		}
+
+		branch := c.Branches[line.address]
+		summary.Branches++
+		summary.BranchOutcomes += 2
+		if branch.Taken != 0 || branch.NotTaken != 0 {
+			summary.ExecutedBranches++
+		}
+		if branch.Taken != 0 {
+			summary.CoveredOutcomes++
+		}
+		if branch.NotTaken != 0 {
+			summary.CoveredOutcomes++
+		}
+	}
+	return summary
+}
+
+func (c *Coverage) WriteSummary(writer io.Writer, context *Context, start int, size int) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c.Summary(context, start, size))
+}
+
+// WriteListing prints the disassembly of the code region with the execution count of every instruction
+// and the outcomes of the conditional jumps, instructions that never ran are marked with ##### like gcov does
+func (c *Coverage) WriteListing(writer io.Writer, context *Context, start int, size int) error {
+	buffered := bufio.NewWriter(writer)
+	for _, line := range coverageLines(context, start, size) {
+		if !line.valid {
+			fmt.Fprintf(buffered, "%10s  %05x  db 0x%02x\n", "-", line.address, context.ReadMemory8(line.address))
+			continue
+		}
+
+		count := "#####"
+		if c.Counts[line.address] != 0 {
+			count = fmt.Sprint(c.Counts[line.address])
+		}
+		text := strings.TrimSuffix(line.instruction.String(), "\n")
+		if line.instruction.Type.IsConditionalJump() {
+			branch := c.Branches[line.address]
+			text = fmt.Sprintf("%-24s ; taken %d, not taken %d", text, branch.Taken, branch.NotTaken)
+		}
+		fmt.Fprintf(buffered, "%10s  %05x  %s\n", count, line.address, text)
+	}
+
+	summary := c.Summary(context, start, size)
+	fmt.Fprintf(buffered, "\ninstructions: %d of %d executed\n", summary.ExecutedInstructions, summary.Instructions)
+	fmt.Fprintf(buffered, "branches: %d of %d executed, %d of %d outcomes covered\n",
+		summary.ExecutedBranches, summary.Branches, summary.CoveredOutcomes, summary.BranchOutcomes)
+	return buffered.Flush()
+}
@@ -0,0 +1,57 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	context := &Context{}
+	program := []byte{
+		0xb9, 0x03, 0x00, // mov cx, 3
+		0xe3, 0x03, // jcxz done
+		0xe2, 0xfc, // loop $-2
+		0xf4, // hlt
+		0xf4, // done: hlt
+	}
+	copy(context.Memory[0x100:], program)
+	context.InstructionPointer = 0x100
+
+	coverage := NewCoverage()
+	context.AddObserver(coverage)
+	result := Run(gocontext.Background(), context, RunOptions{})
+	require.Equal(t, SR_Halted, result.Reason)
+
+	require.Equal(t, uint64(3), coverage.Counts[0x103])
+	require.Equal(t, BranchCoverage{Taken: 0, NotTaken: 3}, coverage.Branches[0x103])
+	require.Equal(t, BranchCoverage{Taken: 2, NotTaken: 1}, coverage.Branches[0x105])
+
+	require.Equal(t, CoverageSummary{
+		Instructions:         5,
+		ExecutedInstructions: 4,
+		Branches:             2,
+		ExecutedBranches:     2,
+		BranchOutcomes:       4,
+		CoveredOutcomes:      3,
+	}, coverage.Summary(context, 0x100, len(program)))
+
+	listing := &strings.Builder{}
+	require.NoError(t, coverage.WriteListing(listing, context, 0x100, len(program)+1))
+	require.Equal(t, `         1  00100  mov cx, word 3
+         3  00103  jcxz $+5                 ; taken 0, not taken 3
+         3  00105  loop $-2                 ; taken 2, not taken 1
+         1  00107  hlt
+     #####  00108  hlt
+         -  00109  db 0x00
+
+instructions: 4 of 5 executed
+branches: 2 of 2 executed, 3 of 4 outcomes covered
+`, listing.String())
+
+	summary := &strings.Builder{}
+	require.NoError(t, coverage.WriteSummary(summary, context, 0x100, len(program)))
+	require.Contains(t, summary.String(), `"coveredOutcomes": 3`)
+}
@@ -0,0 +1,335 @@
+package simulator8086
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CPUTestState is the state before or after a test case of the per-opcode JSON test vectors
+// (like https://github.com/SingleStepTests/8088), the final state only lists the registers that changed
+type CPUTestState struct {
+	Registers map[string]uint16 `json:"regs"`
+	// pairs of physical address and byte
+	RAM   [][2]int `json:"ram"`
+	Queue []int    `json:"queue"`
+}
+
+type CPUTest struct {
+	Name    string       `json:"name"`
+	Bytes   []int        `json:"bytes"`
+	Initial CPUTestState `json:"initial"`
+	Final   CPUTestState `json:"final"`
+	// the bus cycles are not compared, the simulator is not cycle accurate
+	Cycles []json.RawMessage `json:"cycles"`
+	Hash   string            `json:"hash"`
+	Index  int               `json:"idx"`
+}
+
+type CPUTestResult struct {
+	Test   CPUTest
+	Passed bool
+	// the instruction has a prefix, the simulator does not support them
+	Unsupported bool
+	// one line per mismatching register, flag or memory byte, or the error of the simulation
+	Differences []string
+}
+
+type CPUTestFileResult struct {
+	// the name of the file without extensions, like "00" or "F6.4" for a group opcode
+	Opcode      string
+	Passed      int
+	Failed      int
+	Unsupported int
+	Failures    []CPUTestResult
+}
+
+type CPUTestOptions struct {
+	// flags that are undefined after the instructions of an opcode are not compared, see LoadCPUTestMetadata
+	FlagsMasks map[string]uint16
+	// number of failures kept per file, 0 keeps all
+	MaxFailures int
+}
+
+var cpuTestRegisters = []string{"ax", "bx", "cx", "dx", "cs", "ss", "ds", "es", "sp", "bp", "si", "di"}
+
+// the segment override, lock and repeat prefixes
+var cpuTestPrefixes = map[int]bool{0x26: true, 0x2e: true, 0x36: true, 0x3e: true, 0xf0: true, 0xf2: true, 0xf3: true}
+
+var flagNames = []string{"CF", "", "PF", "", "AF", "", "ZF", "SF", "TF", "IF", "DF", "OF"}
+
+func openMaybeGzipped(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, file}, nil
+}
+
+// LoadCPUTests reads the test cases of an opcode from a .json or .json.gz file
+func LoadCPUTests(path string) ([]CPUTest, error) {
+	reader, err := openMaybeGzipped(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	tests := []CPUTest{}
+	err = json.NewDecoder(bufio.NewReader(reader)).Decode(&tests)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tests, nil
+}
+
+type cpuTestOpcodeMetadata struct {
+	FlagsMask *uint16                          `json:"flags-mask"`
+	Registers map[string]cpuTestOpcodeMetadata `json:"reg"`
+}
+
+// LoadCPUTestMetadata reads the flags masks of the metadata file that comes with the test vectors.
+// The masks of group opcodes are stored as "F6.4" like the names of their test files.
+func LoadCPUTestMetadata(path string) (map[string]uint16, error) {
+	reader, err := openMaybeGzipped(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	metadata := struct {
+		Opcodes map[string]cpuTestOpcodeMetadata `json:"opcodes"`
+	}{}
+	err = json.NewDecoder(reader).Decode(&metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	masks := map[string]uint16{}
+	for opcode, entry := range metadata.Opcodes {
+		if entry.FlagsMask != nil {
+			masks[strings.ToUpper(opcode)] = *entry.FlagsMask
+		}
+		for reg, group := range entry.Registers {
+			if group.FlagsMask != nil {
+				masks[strings.ToUpper(opcode)+"."+reg] = *group.FlagsMask
+			}
+		}
+	}
+	return masks, nil
+}
+
+func checkCPUTestRegisters(state CPUTestState) error {
+	for name := range state.Registers {
+		known := name == "ip" || name == "flags"
+		for _, register := range cpuTestRegisters {
+			known = known || name == register
+		}
+		if !known {
+			return fmt.Errorf("unknown register %q", name)
+		}
+	}
+	return nil
+}
+
+func setCPUTestState(context *Context, state CPUTestState) {
+	for name, value := range state.Registers {
+		switch name {
+		case "ip":
+			context.InstructionPointer = int16(value)
+		case "flags":
+			context.SetFlagsWord(value)
+		default:
+			context.SetRegister(RegisterName(name), int16(value))
+		}
+	}
+	for _, entry := range state.RAM {
+		context.WriteMemory8(entry[0], byte(entry[1]))
+	}
+}
+
+func flagDifferences(expected uint16, actual uint16) string {
+	names := []string{}
+	for bit, name := range flagNames {
+		if name != "" && (expected^actual)&(1<<bit) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// RunCPUTest executes one instruction and compares the registers and memory with the final state.
+// A flagsMask of 0 compares all flags. Instructions with a prefix are not executed but unsupported.
+func RunCPUTest(test CPUTest, flagsMask uint16) CPUTestResult {
+	if flagsMask == 0 {
+		flagsMask = 0xffff
+	}
+
+	result := CPUTestResult{Test: test}
+	if len(test.Bytes) != 0 && cpuTestPrefixes[test.Bytes[0]] {
+		result.Unsupported = true
+		return result
+	}
+	for _, state := range []CPUTestState{test.Initial, test.Final} {
+		if err := checkCPUTestRegisters(state); err != nil {
+			result.Differences = append(result.Differences, fmt.Sprintf("error: %s", err))
+			return result
+		}
+	}
+
+	context := &Context{}
+	setCPUTestState(context, test.Initial)
+	_, err := Step(context)
+	if err != nil {
+		result.Differences = append(result.Differences, fmt.Sprintf("error: %s", err))
+		return result
+	}
+
+	expected := map[string]uint16{}
+	for name, value := range test.Initial.Registers {
+		expected[name] = value
+	}
+	for name, value := range test.Final.Registers {
+		expected[name] = value
+	}
+
+	for _, name := range cpuTestRegisters {
+		value, ok := expected[name]
+		actual := uint16(context.GetRegister(RegisterName(name)))
+		if ok && actual != value {
+			result.Differences = append(result.Differences, fmt.Sprintf("%s: expected 0x%04x, got 0x%04x", name, value, actual))
+		}
+	}
+	if value, ok := expected["ip"]; ok && uint16(context.InstructionPointer) != value {
+		result.Differences = append(result.Differences, fmt.Sprintf("ip: expected 0x%04x, got 0x%04x", value, uint16(context.InstructionPointer)))
+	}
+	if value, ok := expected["flags"]; ok && (context.FlagsWord()^value)&flagsMask != 0 {
+		actual := context.FlagsWord()
+		result.Differences = append(result.Differences, fmt.Sprintf("flags: expected 0x%04x, got 0x%04x (%s)",
+			value&flagsMask, actual&flagsMask, flagDifferences(value&flagsMask, actual&flagsMask)))
+	}
+	for _, entry := range test.Final.RAM {
+		actual := context.ReadMemory8(entry[0])
+		if actual != byte(entry[1]) {
+			result.Differences = append(result.Differences, fmt.Sprintf("memory 0x%05x: expected 0x%02x, got 0x%02x", entry[0], entry[1], actual))
+		}
+	}
+
+	result.Passed = len(result.Differences) == 0
+	return result
+}
+
+func cpuTestOpcode(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.ToUpper(strings.TrimSuffix(name, ".json"))
+}
+
+func RunCPUTestFile(path string, options CPUTestOptions) (CPUTestFileResult, error) {
+	opcode := cpuTestOpcode(path)
+	result := CPUTestFileResult{Opcode: opcode}
+	tests, err := LoadCPUTests(path)
+	if err != nil {
+		return result, err
+	}
+
+	for _, test := range tests {
+		testResult := RunCPUTest(test, options.FlagsMasks[opcode])
+		if testResult.Unsupported {
+			result.Unsupported++
+			continue
+		}
+		if testResult.Passed {
+			result.Passed++
+			continue
+		}
+		result.Failed++
+		if options.MaxFailures == 0 || len(result.Failures) < options.MaxFailures {
+			result.Failures = append(result.Failures, testResult)
+		}
+	}
+	return result, nil
+}
+
+// RunCPUTestDirectory runs every .json and .json.gz file of the directory, except for the metadata
+func RunCPUTestDirectory(directory string, options CPUTestOptions) ([]CPUTestFileResult, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")) {
+			continue
+		}
+		if _, err := strconv.ParseUint(strings.Split(cpuTestOpcode(name), ".")[0], 16, 8); err != nil {
+			continue
+		}
+		paths = append(paths, filepath.Join(directory, name))
+	}
+	sort.Slice(paths, func(i, j int) bool { return cpuTestOpcode(paths[i]) < cpuTestOpcode(paths[j]) })
+
+	results := make([]CPUTestFileResult, 0, len(paths))
+	for _, path := range paths {
+		result, err := RunCPUTestFile(path, options)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// WriteCPUTestReport prints the passed and failed cases per opcode and the differences of the kept failures.
+// Unsupported cases are counted separately.
+func WriteCPUTestReport(writer io.Writer, results []CPUTestFileResult) error {
+	buffered := bufio.NewWriter(writer)
+	passed, failed, unsupported := 0, 0, 0
+	for _, result := range results {
+		passed += result.Passed
+		failed += result.Failed
+		unsupported += result.Unsupported
+		status := "PASS"
+		if result.Failed != 0 {
+			status = "FAIL"
+		} else if result.Passed == 0 {
+			status = "SKIP"
+		}
+		fmt.Fprintf(buffered, "%-6s %s %d/%d", result.Opcode, status, result.Passed, result.Passed+result.Failed)
+		if result.Unsupported != 0 {
+			fmt.Fprintf(buffered, ", %d unsupported", result.Unsupported)
+		}
+		fmt.Fprintln(buffered)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(buffered, "  #%d %s\n", failure.Test.Index, failure.Test.Name)
+			for _, difference := range failure.Differences {
+				fmt.Fprintf(buffered, "    %s\n", difference)
+			}
+		}
+	}
+	fmt.Fprintf(buffered, "passed %d of %d test cases", passed, passed+failed)
+	if unsupported != 0 {
+		fmt.Fprintf(buffered, ", %d unsupported", unsupported)
+	}
+	fmt.Fprintln(buffered)
+	return buffered.Flush()
+}
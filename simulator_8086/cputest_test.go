@@ -0,0 +1,100 @@
+package simulator8086
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const cpuTestMovAX = `[
+{"name": "mov ax, 1234h", "bytes": [184, 52, 18],
+ "initial": {"regs": {"ax": 0, "bx": 1, "cx": 2, "dx": 3, "cs": 4096, "ss": 0, "ds": 0, "es": 0, "sp": 256, "bp": 0, "si": 0, "di": 0, "ip": 16, "flags": 61442},
+  "ram": [[65552, 184], [65553, 52], [65554, 18]], "queue": []},
+ "final": {"regs": {"ax": 4660, "ip": 19}, "ram": [[65552, 184], [65553, 52], [65554, 18]], "queue": []},
+ "cycles": [[0, 0, "--", "---", "---", 0, 0, "PASV", "-", "T1"]], "hash": "a", "idx": 0},
+{"name": "mov ax, 1234h", "bytes": [184, 52, 18],
+ "initial": {"regs": {"ax": 0, "bx": 1, "cx": 2, "dx": 3, "cs": 4096, "ss": 0, "ds": 0, "es": 0, "sp": 256, "bp": 0, "si": 0, "di": 0, "ip": 16, "flags": 61442},
+  "ram": [[65552, 184], [65553, 52], [65554, 18], [512, 1]], "queue": []},
+ "final": {"regs": {"ax": 4661, "ip": 19}, "ram": [[512, 2]], "queue": []},
+ "hash": "b", "idx": 1}
+]`
+
+const cpuTestClearCarry = `[
+{"name": "clc", "bytes": [248],
+ "initial": {"regs": {"ax": 0, "bx": 0, "cx": 0, "dx": 0, "cs": 0, "ss": 0, "ds": 0, "es": 0, "sp": 0, "bp": 0, "si": 0, "di": 0, "ip": 256, "flags": 61443},
+  "ram": [[256, 248]], "queue": []},
+ "final": {"regs": {"ip": 257, "flags": 61458}, "ram": [], "queue": []},
+ "hash": "c", "idx": 0}
+]`
+
+const cpuTestSegmentOverride = `[
+{"name": "mov ax, es:[bx]", "bytes": [38, 139, 7],
+ "initial": {"regs": {"ax": 0, "bx": 0, "cx": 0, "dx": 0, "cs": 0, "ss": 0, "ds": 0, "es": 16, "sp": 0, "bp": 0, "si": 0, "di": 0, "ip": 256, "flags": 61442},
+  "ram": [[256, 38], [257, 139], [258, 7]], "queue": []},
+ "final": {"regs": {"ax": 35622, "ip": 259}, "ram": [], "queue": []},
+ "hash": "d", "idx": 0}
+]`
+
+func TestCPUTests(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "B8.json"), []byte(cpuTestMovAX), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "26.json"), []byte(cpuTestSegmentOverride), 0644))
+	file, err := os.Create(filepath.Join(directory, "F8.json.gz"))
+	require.NoError(t, err)
+	compressed := gzip.NewWriter(file)
+	_, err = compressed.Write([]byte(cpuTestClearCarry))
+	require.NoError(t, err)
+	require.NoError(t, compressed.Close())
+	require.NoError(t, file.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "8088.json"),
+		[]byte(`{"opcodes": {"F8": {"status": "normal", "flags-mask": 65519}, "F6": {"reg": {"4": {"flags-mask": 2260}}}}}`), 0644))
+
+	masks, err := LoadCPUTestMetadata(filepath.Join(directory, "8088.json"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint16{"F8": 0xffef, "F6.4": 0x08d4}, masks)
+
+	results, err := RunCPUTestDirectory(directory, CPUTestOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	// prefixes are not supported, the cases neither pass nor fail
+	require.Equal(t, CPUTestFileResult{Opcode: "26", Unsupported: 1}, results[0])
+	require.Equal(t, "B8", results[1].Opcode)
+	require.Equal(t, 1, results[1].Passed)
+	require.Equal(t, 1, results[1].Failed)
+	require.Equal(t, []string{
+		"ax: expected 0x1235, got 0x1234",
+		"memory 0x00200: expected 0x02, got 0x01",
+	}, results[1].Failures[0].Differences)
+	// the auxiliary carry flag is only ignored with the mask of the metadata
+	require.Equal(t, []string{"flags: expected 0xf012, got 0xf002 (AF)"}, results[2].Failures[0].Differences)
+
+	results, err = RunCPUTestDirectory(directory, CPUTestOptions{FlagsMasks: masks})
+	require.NoError(t, err)
+	require.Equal(t, 1, results[2].Passed)
+
+	report := &strings.Builder{}
+	require.NoError(t, WriteCPUTestReport(report, results))
+	require.Equal(t, `26     SKIP 0/0, 1 unsupported
+B8     FAIL 1/2
+  #1 mov ax, 1234h
+    ax: expected 0x1235, got 0x1234
+    memory 0x00200: expected 0x02, got 0x01
+F8     PASS 1/1
+passed 2 of 3 test cases, 1 unsupported
+`, report.String())
+}
+
+func TestCPUTestUnknownRegister(t *testing.T) {
+	tests := []CPUTest{}
+	require.NoError(t, json.Unmarshal([]byte(cpuTestMovAX), &tests))
+	test := tests[0]
+	test.Final.Registers = map[string]uint16{"ax": 4660, "xx": 1}
+	result := RunCPUTest(test, 0)
+	require.False(t, result.Passed)
+	require.Equal(t, []string{`error: unknown register "xx"`}, result.Differences)
+}
@@ -0,0 +1,93 @@
+package simulator8086
+
+import "testing"
+
+// a mix of the instructions of the course listings, encoded once and decoded repeatedly
+var benchmarkProgram = []byte{
+	0x89, 0xd9, // mov cx, bx
+	0x8b, 0x56, 0x00, // mov dx, [bp]
+	0xc6, 0x03, 0x07, // mov byte [bp + di], 7
+	0xb8, 0x34, 0x12, // mov ax, 0x1234
+	0xa1, 0x00, 0x20, // mov ax, [0x2000]
+	0x03, 0x18, // add bx, [bx + si]
+	0x83, 0xc6, 0x02, // add si, 2
+	0x81, 0xec, 0x88, 0x01, // sub sp, 392
+	0x29, 0xc3, // sub bx, ax
+	0x3b, 0x46, 0x06, // cmp ax, [bp + 6]
+	0x3c, 0x09, // cmp al, 9
+	0x75, 0xfe, // jne $
+	0xe2, 0xfe, // loop $
+	0x50,                   // push ax
+	0x5b,                   // pop bx
+	0xff, 0x36, 0x00, 0x20, // push word [0x2000]
+	0x40,       // inc ax
+	0xfe, 0xc8, // dec al
+	0xf7, 0xe3, // mul bx
+	0xd1, 0xe0, // shl ax, 1
+	0x86, 0xc4, // xchg al, ah
+	0xe8, 0x00, 0x00, // call $+3
+	0xc3,       // ret
+	0xcd, 0x21, // int 21h
+	0xf3,       // rep
+	0xa4,       // movsb
+	0xe4, 0x60, // in al, 0x60
+	0xf8, // clc
+}
+
+// benchmarkOffsets returns the offsets of the instructions of the benchmark program
+func benchmarkOffsets(b *testing.B) []int {
+	offsets := []int{}
+	for offset := 0; offset < len(benchmarkProgram); {
+		instruction, err := DecodeInstruction(benchmarkProgram[offset:])
+		if err != nil {
+			b.Fatal(err)
+		}
+		offsets = append(offsets, offset)
+		offset += instruction.SizeInBytes
+	}
+	return offsets
+}
+
+func BenchmarkInstructionTypeFromBytes(b *testing.B) {
+	offsets := benchmarkOffsets(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, offset := range offsets {
+			_, err := InstructionTypeFromBytes(benchmarkProgram[offset:])
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(len(offsets)*b.N)/b.Elapsed().Seconds(), "instructions/s")
+}
+
+func BenchmarkDecodeInstruction(b *testing.B) {
+	count := len(benchmarkOffsets(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for offset := 0; offset < len(benchmarkProgram); {
+			instruction, err := DecodeInstruction(benchmarkProgram[offset:])
+			if err != nil {
+				b.Fatal(err)
+			}
+			offset += instruction.SizeInBytes
+		}
+	}
+	b.ReportMetric(float64(count*b.N)/b.Elapsed().Seconds(), "instructions/s")
+}
+
+func BenchmarkAppendInstructions(b *testing.B) {
+	instructions := make([]Instruction, 0, len(benchmarkOffsets(b)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		instructions, err = AppendInstructions(instructions[:0], benchmarkProgram)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(instructions)*b.N)/b.Elapsed().Seconds(), "instructions/s")
+}
@@ -1,8 +1,6 @@
 package simulator8086
 
-import (
-	"errors"
-)
+import "math/bits"
 
 func parse16BitValue(content []byte) int16 {
 	lowByte := int16(content[0])
@@ -53,446 +51,166 @@ func StringifyInstructions(instructions []Instruction) string {
 }
 
 func Disassemble(content []byte) ([]Instruction, error) {
-	instructions := make([]Instruction, 0)
+	return AppendInstructions(make([]Instruction, 0), content)
+}
+
+// AppendInstructions decodes content and appends the instructions to instructions, decoding into a
+// buffer with enough capacity does not allocate
+func AppendInstructions(instructions []Instruction, content []byte) ([]Instruction, error) {
 	currentByte := 0
 	for currentByte < len(content) {
-		startByte := currentByte
-
-		instructionType, err := InstructionTypeFromBytes(content[currentByte:])
+		instruction, err := DecodeInstruction(content[currentByte:])
 		if err != nil {
 			return instructions, err
 		}
 
-		b1 := content[currentByte]
-		currentByte++
-
-		if instructionType == IT_PushReg || instructionType == IT_PopReg || instructionType == IT_ExchangeRegWithAcc || instructionType == IT_IncReg || instructionType == IT_DecReg {
-			reg := b1 & 0b111
-			var src *DataLocation
-			dst := &DataLocation{
-				Type:         DL_Register,
-				RegisterName: registerTable[1][reg],
-			}
-			if instructionType == IT_ExchangeRegWithAcc {
-				src = dst
-				dst = &DataLocation{
-					Type:         DL_Register,
-					RegisterName: AX,
-				}
-			}
-			instructions = append(instructions, Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Destination: dst,
-				Source:      src,
-			})
-			continue
-		}
-
-		if instructionType == IT_PushSegReg || instructionType == IT_PopSegReg {
-			reg := (b1 >> 3) & 0b11
-			instructions = append(instructions, Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Destination: &DataLocation{
-					Type:         DL_Register,
-					RegisterName: segmentRegisterTable[reg],
-				},
-			})
-			continue
-		}
-
-		if instructionType.IsSingleByteInstruction() {
-			w := b1 & 0b1
-			instructions = append(instructions, Instruction{
-				Type:        instructionType,
-				SizeInBytes: 1,
-				Wide:        w == 0b1,
-			})
-			continue
-		}
-
-		if instructionType.IsConditionalJump() {
-			offset := int8(content[currentByte])
-			currentByte++
-
-			instruction := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Destination: &DataLocation{
-					Type:          DL_Label,
-					LabelPosition: int(offset + 2),
-				},
-			}
-			instructions = append(instructions, instruction)
-			continue
-		}
-
-		if instructionType == IT_ReturnWithinSegmentAddingImmediateToSP || instructionType == IT_ReturnIntersegmentAddingImmediateToSP {
-			parsedBytes, data := parseData(content[currentByte:], true)
-			currentByte += int(parsedBytes)
-			instructions = append(instructions, Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Destination: &DataLocation{
-					Type:           DL_Immediate,
-					ImmediateValue: data,
-					AvoidSizeInfo:  true,
-				},
-			})
-			continue
-		}
-
-		if instructionType == IT_InterruptTypeSpecified {
-			parsedBytes, data := parseData(content[currentByte:], false)
-			currentByte += int(parsedBytes)
-			instructions = append(instructions, Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Destination: &DataLocation{
-					Type:           DL_Immediate,
-					ImmediateValue: data,
-					AvoidSizeInfo:  true,
-				},
-			})
-			continue
-		}
-
-		if instructionType == IT_MovImToReg {
-			w := (b1 >> 3) & 0b00000001
-			reg := b1 & 0b00000111
-
-			parsedBytes, data := parseData(content[currentByte:], w == 0b1)
-			currentByte += parsedBytes
-
-			src := DataLocation{
-				Type:           DL_Immediate,
-				ImmediateValue: data,
-				Wide:           w == 0b1,
-			}
-			dst := DataLocation{
-				Type:         DL_Register,
-				RegisterName: registerTable[w][reg],
-			}
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      &src,
-				Destination: &dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
-
-		w := b1 & 0b1
-
-		if instructionType.IsInOut() {
-			var src DataLocation
-			if instructionType == IT_InVariable || instructionType == IT_OutVariable {
-				src = DataLocation{
-					Type:         DL_Register,
-					RegisterName: DX,
-				}
-			} else {
-				parsedBytes, data := parseData(content[currentByte:], false)
-				currentByte += parsedBytes
-				src = DataLocation{
-					Type:           DL_Immediate,
-					ImmediateValue: data,
-				}
-			}
-			dstRegisterName := AL
-			if w == 0b1 {
-				dstRegisterName = AX
-			}
-			dst := DataLocation{
-				Type:         DL_Register,
-				RegisterName: dstRegisterName,
-			}
-			if instructionType == IT_OutFixed || instructionType == IT_OutVariable {
-				tmp := src
-				src = dst
-				dst = tmp
-			}
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      &src,
-				Destination: &dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
-
-		if instructionType == IT_MovMemToAcc {
-			displacement := parse16BitValue(content[currentByte:])
-			currentByte += 2
-
-			src := DataLocation{
-				Type: DL_Memory,
-				AddressCalculation: AddressCalculation{
-					Type:         ACT_DirectAddress,
-					Displacement: displacement,
-				},
-				Wide: w == 0b1,
-			}
-			dst := DataLocation{
-				Type:         DL_Register,
-				RegisterName: AX,
-			}
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      &src,
-				Destination: &dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
-
-		if instructionType == IT_MovAccToMem {
-			displacement := parse16BitValue(content[currentByte:])
-			currentByte += 2
-
-			src := DataLocation{
-				Type:         DL_Register,
-				RegisterName: AX,
-			}
-			dst := DataLocation{
-				Type: DL_Memory,
-				AddressCalculation: AddressCalculation{
-					Type:         ACT_DirectAddress,
-					Displacement: displacement,
-				},
-				Wide: w == 0b1,
-			}
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      &src,
-				Destination: &dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
-
-		if instructionType.IsImToAcc() {
-			parsedBytes, data := parseData(content[currentByte:], w == 0b1)
-			currentByte += parsedBytes
-
-			src := DataLocation{
-				Type:           DL_Immediate,
-				ImmediateValue: data,
-				Wide:           w == 0b1,
-			}
-			dst := DataLocation{
-				Type:         DL_Register,
-				RegisterName: registerTable[w][0],
-			}
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      &src,
-				Destination: &dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
-
-		b2 := content[currentByte]
-		currentByte++
-
-		mod := b2 >> 6
-		reg := (b2 >> 3) & 0b111
-		rm := b2 & 0b111
-
-		if mod == 0b11 {
-			if instructionType.IsRegMemWithRegToEither() {
-				// Register Mode (no displacement)
-				src := &DataLocation{
-					Type:         DL_Register,
-					RegisterName: registerTable[w][reg],
-				}
-				dst := &DataLocation{
-					Type:         DL_Register,
-					RegisterName: registerTable[w][rm],
-				}
-				if instructionType == IT_ExchangeRegMemWithReg {
-					tmp := src
-					src = dst
-					dst = tmp
-				}
-				if instructionType.IsSingleOperandInstruction() {
-					src = nil
-				}
-				if instructionType == IT_MovRegMemToSegReg {
-					src.RegisterName = registerTable[1][rm]
-					dst.RegisterName = segmentRegisterTable[reg]
-				}
-				if instructionType == IT_MovSegRegToRegMem {
-					src.RegisterName = segmentRegisterTable[reg]
-					dst.RegisterName = registerTable[1][rm]
-				}
-
-				if instructionType.IsShiftOrRotateInstruction() {
-					v := (b1 >> 1) & 0b1
-					if v == 0b0 {
-						src = &DataLocation{Type: DL_Immediate, ImmediateValue: 1, AvoidSizeInfo: true}
-					} else {
-						src = &DataLocation{Type: DL_Register, RegisterName: CL}
-					}
-				}
-
-				inst := Instruction{
-					Type:        instructionType,
-					SizeInBytes: currentByte - startByte,
-					Source:      src,
-					Destination: dst,
-				}
-				instructions = append(instructions, inst)
-				continue
-			}
-
-			s := (b1 >> 1) & 0b1
-			wide := s == 0b0 && w == 0b1
-			parsedBytes, data := parseData(content[currentByte:], wide)
-			currentByte += parsedBytes
-
-			src := DataLocation{
-				Type:           DL_Immediate,
-				ImmediateValue: data,
-				Wide:           w == 0b1,
-			}
-			dst := DataLocation{
-				Type:         DL_Register,
-				RegisterName: registerTable[w][rm],
-			}
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      &src,
-				Destination: &dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
-
-		parsedBytes, addressCalculation := parseAddressCalculation(content[currentByte:], mod, rm)
-		currentByte += parsedBytes
-
-		if instructionType.IsRegMemWithRegToEither() {
-			src := &DataLocation{}
-			dst := &DataLocation{}
-
-			if instructionType.IsShiftOrRotateInstruction() {
-				v := (b1 >> 1) & 0b1
-				if v == 0b0 {
-					src = &DataLocation{Type: DL_Immediate, ImmediateValue: 1, AvoidSizeInfo: true}
-				} else {
-					src = &DataLocation{Type: DL_Register, RegisterName: CL}
-				}
-				dst.Type = DL_Memory
-				dst.AddressCalculation = addressCalculation
-				dst.Wide = w == 0b1
-			} else {
-				d := (b1 >> 1) & 0b1
-				if d == 0b1 || instructionType.AlwaysToRegister() {
-					if instructionType == IT_LoadES {
-						w = 0b1
-					}
-					dst.Type = DL_Register
-					dst.RegisterName = registerTable[w][reg]
-					src.Type = DL_Memory
-					src.AddressCalculation = addressCalculation
-					src.Wide = w == 0b1
-					src.AvoidSizeInfo = instructionType == IT_LoadDS || instructionType == IT_LoadES
-				} else {
-					src.Type = DL_Register
-					src.RegisterName = registerTable[w][reg]
-					dst.Type = DL_Memory
-					dst.AddressCalculation = addressCalculation
-					dst.Wide = w == 0b1
-				}
-			}
-
-			if instructionType.IsSingleOperandInstruction() {
-				dst = src
-				src = nil
-			}
+		instructions = append(instructions, instruction)
+		currentByte += instruction.SizeInBytes
+	}
 
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      src,
-				Destination: dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
+	return instructions, nil
+}
 
-		if instructionType.IsImToRegMem() {
-			wide := w == 0b1
-			if instructionType.HasSignExtension() {
-				s := (b1 >> 1) & 0b1
-				wide = wide && s == 0b0
-			}
-			parsedBytes, data := parseData(content[currentByte:], wide)
-			currentByte += parsedBytes
-			src := DataLocation{
-				Type:           DL_Immediate,
-				ImmediateValue: data,
-				Wide:           w == 0b1,
-			}
+// decodedFields are the fields of an instruction its operands are built from
+type decodedFields struct {
+	wide     bool
+	count    bool
+	reg      byte
+	segment  byte
+	escape   byte
+	mod      byte
+	rm       byte
+	address  AddressCalculation
+	value    int16
+	farValue int16
+	size     int
+}
 
-			var dst DataLocation
-			if mod == 0b11 {
-				dst = DataLocation{
-					Type:         DL_Register,
-					RegisterName: registerTable[w][reg],
-				}
-			} else {
-				dst = DataLocation{
-					Type:               DL_Memory,
-					AddressCalculation: addressCalculation,
-					Wide:               w == 0b1,
-				}
-			}
+// field extracts the bits of mask from b, mask is 0 if the encoding does not have the field
+func field(b byte, mask byte) byte {
+	return (b & mask) >> bits.TrailingZeros8(mask|0x80)
+}
 
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Source:      &src,
-				Destination: &dst,
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
+func (f *decodedFields) operand(kind operandKind) DataLocation {
+	w := 0
+	if f.wide {
+		w = 1
+	}
+	switch kind {
+	case operandRegisterMemory, operandFarPointer:
+		if f.mod == 0b11 {
+			return DataLocation{Type: DL_Register, RegisterName: registerTable[w][f.rm]}
+		}
+		return DataLocation{
+			Type:               DL_Memory,
+			AddressCalculation: f.address,
+			Wide:               f.wide,
+			AvoidSizeInfo:      kind == operandFarPointer,
+		}
+	case operandRegister:
+		return DataLocation{Type: DL_Register, RegisterName: registerTable[w][f.reg]}
+	case operandSegment:
+		return DataLocation{Type: DL_Register, RegisterName: segmentRegisterTable[f.segment]}
+	case operandAccumulator:
+		return DataLocation{Type: DL_Register, RegisterName: registerTable[w][0]}
+	case operandDX:
+		return DataLocation{Type: DL_Register, RegisterName: DX}
+	case operandData:
+		return DataLocation{Type: DL_Immediate, ImmediateValue: f.value, Wide: f.wide}
+	case operandData8, operandData16:
+		return DataLocation{Type: DL_Immediate, ImmediateValue: f.value, AvoidSizeInfo: true}
+	case operandPort:
+		return DataLocation{Type: DL_Immediate, ImmediateValue: f.value}
+	case operandAddress:
+		return DataLocation{
+			Type:               DL_Memory,
+			AddressCalculation: AddressCalculation{Type: ACT_DirectAddress, Displacement: f.value},
+			Wide:               f.wide,
+		}
+	case operandCount:
+		if f.count {
+			return DataLocation{Type: DL_Register, RegisterName: CL}
+		}
+		return DataLocation{Type: DL_Immediate, ImmediateValue: 1, AvoidSizeInfo: true}
+	case operandLabel8, operandLabel16:
+		// labels are relative to the start of the instruction
+		return DataLocation{Type: DL_Label, LabelPosition: int(f.value) + f.size}
+	case operandFar:
+		return DataLocation{Type: DL_FarAddress, ImmediateValue: f.value, Segment: f.farValue}
+	case operandEscape:
+		return DataLocation{Type: DL_Immediate, ImmediateValue: int16(f.escape), AvoidSizeInfo: true}
+	}
+	return DataLocation{}
+}
 
-		if instructionType == IT_PushRegMem || instructionType == IT_PopRegMem {
-			inst := Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-				Destination: &DataLocation{
-					Type:               DL_Memory,
-					AddressCalculation: addressCalculation,
-					Wide:               true,
-				},
-			}
-			instructions = append(instructions, inst)
-			continue
-		}
+// DecodeInstruction decodes the instruction at the start of content by its row of instructions.txt
+func DecodeInstruction(content []byte) (Instruction, error) {
+	instructionType, err := InstructionTypeFromBytes(content)
+	if err != nil {
+		return Instruction{}, err
+	}
+	encoding := &instructionEncodings[instructionType]
+
+	b1 := content[0]
+	fields := decodedFields{
+		wide:    encoding.w == 0 || b1&encoding.w != 0,
+		count:   b1&encoding.v != 0,
+		reg:     field(b1, encoding.register),
+		segment: field(b1, encoding.segment),
+		escape:  field(b1, encoding.escape),
+		size:    1,
+	}
 
-		if instructionType == IT_AsciiAdjustForMultiply || instructionType == IT_AsciiAdjustForDivide {
-			// TODO the manual says that these instructions are actually 4 bytes, but it works like this
-			instructions = append(instructions, Instruction{
-				Type:        instructionType,
-				SizeInBytes: currentByte - startByte,
-			})
-			continue
+	if encoding.modrm != modrmNone {
+		b2 := content[1]
+		fields.size++
+		fields.mod = b2 >> 6
+		fields.rm = b2 & 0b111
+		switch encoding.modrm {
+		case modrmRegister:
+			fields.reg = (b2 >> 3) & 0b111
+		case modrmSegment:
+			fields.segment = (b2 >> 3) & 0b11
+		case modrmEscape:
+			fields.escape = fields.escape<<3 | (b2>>3)&0b111
+		}
+		if fields.mod != 0b11 {
+			parsedBytes, addressCalculation := parseAddressCalculation(content[fields.size:], fields.mod, fields.rm)
+			fields.size += parsedBytes
+			fields.address = addressCalculation
 		}
+	}
+	if encoding.hasSecondByte {
+		fields.size++
+	}
 
-		return instructions, errors.New("instruction decode not implemented yet")
+	for _, kind := range encoding.operands {
+		parsedBytes := 0
+		switch kind {
+		case operandData:
+			parsedBytes, fields.value = parseData(content[fields.size:], fields.wide && b1&encoding.s == 0)
+		case operandData8, operandPort, operandLabel8:
+			parsedBytes, fields.value = parseData(content[fields.size:], false)
+		case operandData16, operandAddress, operandLabel16:
+			parsedBytes, fields.value = parseData(content[fields.size:], true)
+		case operandFar:
+			fields.value = parse16BitValue(content[fields.size:])
+			fields.farValue = parse16BitValue(content[fields.size+2:])
+			parsedBytes = 4
+		}
+		fields.size += parsedBytes
 	}
 
-	return instructions, nil
+	destination := fields.operand(encoding.operands[0])
+	source := fields.operand(encoding.operands[1])
+	if b1&encoding.d != 0 {
+		destination, source = source, destination
+	}
+	return Instruction{
+		Type:        instructionType,
+		SizeInBytes: fields.size,
+		Wide:        b1&encoding.w != 0,
+		Destination: destination,
+		Source:      source,
+	}, nil
 }
@@ -127,3 +127,87 @@ func TestDisassemble(t *testing.T) {
 		})
 	}
 }
+
+func TestInstructionTypeFromBytes(t *testing.T) {
+	cases := []struct {
+		content  []byte
+		expected InstructionType
+	}{
+		{[]byte{0x83, 0xc6}, IT_AddImToRegMem},
+		{[]byte{0x80, 0xcc}, IT_OrImToRegMem},
+		{[]byte{0x83, 0xcc}, IT_Invalid},
+		{[]byte{0xd1, 0xf8}, IT_ShiftArithmeticRight},
+		{[]byte{0xd1, 0xf0}, IT_Invalid},
+		{[]byte{0xf7, 0xe3}, IT_Multiply},
+		{[]byte{0xfe, 0xd0}, IT_Invalid},
+		{[]byte{0xff, 0x36}, IT_PushRegMem},
+		{[]byte{0xff, 0xd3}, IT_CallIndirectWithinSegment},
+		{[]byte{0xd4, 0x0a}, IT_AsciiAdjustForMultiply},
+		{[]byte{0xd4, 0x10}, IT_Invalid},
+		{[]byte{0x7c, 0x00}, IT_JL},
+		{[]byte{0x2e, 0x00}, IT_Invalid},
+	}
+	for _, c := range cases {
+		instructionType, err := InstructionTypeFromBytes(c.content)
+		require.Equal(t, c.expected, instructionType, "% x", c.content)
+		if c.expected == IT_Invalid {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+}
+
+// the encodings the decoder got wrong before it used instructions.txt
+func TestDecodeCorrectedEncodings(t *testing.T) {
+	cases := []struct {
+		content []byte
+		text    string
+	}{
+		// the d bit applies in register mode too
+		{[]byte{0x02, 0xc1}, "add al, cl"},
+		{[]byte{0x00, 0xc1}, "add cl, al"},
+		{[]byte{0xa0, 0x34, 0x12}, "mov al, byte [4660]"},
+		{[]byte{0xa2, 0x34, 0x12}, "mov byte [4660], al"},
+		{[]byte{0x8c, 0x1f}, "mov word [bx], ds"},
+		{[]byte{0x8e, 0x47, 0x02}, "mov es, word [bx + 2]"},
+		{[]byte{0x8c, 0xd8}, "mov ax, ds"},
+		{[]byte{0x83, 0xdb, 0xff}, "sbb bx, word -1"},
+		{[]byte{0x83, 0x1f, 0x80}, "sbb word [bx], word -128"},
+		{[]byte{0xc7, 0xc0, 0x34, 0x12}, "mov ax, word 4660"},
+		{[]byte{0xf7, 0xc1, 0x34, 0x12}, "test cx, word 4660"},
+		{[]byte{0xff, 0xf0}, "push ax"},
+		{[]byte{0x8f, 0xc1}, "pop cx"},
+		// the 8086 does not reject a register operand, it is decoded like the memory form
+		{[]byte{0xc4, 0xc1}, "les ax, cx"},
+		{[]byte{0xc5, 0x07}, "lds ax, [bx]"},
+		{[]byte{0xd8, 0x07}, "esc 0, [bx]"},
+		{[]byte{0xd9, 0x86, 0x34, 0x12}, "esc 8, [bp + 4660]"},
+		{[]byte{0xdf, 0xf9}, "esc 63, cx"},
+	}
+	for _, c := range cases {
+		// only the first instruction is decoded, the bytes after it are ignored
+		content := append(append([]byte{}, c.content...), 0x90, 0x90, 0x90, 0x90)
+		instruction, err := DecodeInstruction(content)
+		require.NoError(t, err, "% x", c.content)
+		require.Equal(t, len(c.content), instruction.SizeInBytes, "% x", c.content)
+		require.Equal(t, c.text+"\n", instruction.String(), "% x", c.content)
+	}
+
+	// the unused rows of the groups and sr values above 3
+	for _, content := range [][]byte{{0xff, 0xff}, {0xfe, 0xd0}, {0x8f, 0xc8}, {0xf6, 0xc8}, {0x8e, 0xf8}} {
+		_, err := DecodeInstruction(append(content, 0x90, 0x90, 0x90, 0x90))
+		require.Error(t, err, "% x", content)
+	}
+}
+
+func TestAppendInstructionsAllocations(t *testing.T) {
+	instructions, err := Disassemble(benchmarkProgram)
+	require.NoError(t, err)
+
+	allocations := testing.AllocsPerRun(100, func() {
+		instructions, err = AppendInstructions(instructions[:0], benchmarkProgram)
+	})
+	require.NoError(t, err)
+	require.Zero(t, allocations)
+}
@@ -0,0 +1,201 @@
+package simulator8086
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	comLoadOffset = 0x100
+	// the rest of the segment after the PSP
+	comMaxSize     = 0x10000 - comLoadOffset
+	pspCommandTail = 0x80
+	// first segment after the 640K of conventional memory
+	memoryTopSegment = 0xa000
+	dosMajorVersion  = 5
+)
+
+// writePSP writes the program segment prefix, memoryTop is the first segment after the memory allocated for the program
+func writePSP(context *Context, pspSegment uint16, memoryTop uint16, commandTail string) error {
+	if len(commandTail) > 126 {
+		return fmt.Errorf("command tail is %d characters long, at most 126 are supported", len(commandTail))
+	}
+
+	psp := PhysicalAddress(int16(pspSegment), 0)
+	// int 20h at offset 0, so that a ret from the program terminates it
+	context.WriteMemory8(psp, 0xcd)
+	context.WriteMemory8(psp+1, 0x20)
+	context.WriteMemory16(psp+2, memoryTop)
+
+	context.WriteMemory8(psp+pspCommandTail, byte(len(commandTail)))
+	for i := 0; i < len(commandTail); i++ {
+		context.WriteMemory8(psp+pspCommandTail+1+i, commandTail[i])
+	}
+	context.WriteMemory8(psp+pspCommandTail+1+len(commandTail), '\r')
+	return nil
+}
+
+// LoadCOM creates a context with the program segment prefix at pspSegment:0000 and the program at pspSegment:0100
+func LoadCOM(program []byte, pspSegment uint16, commandTail string) (*Context, error) {
+	if len(program) > comMaxSize {
+		return nil, fmt.Errorf("COM program is %d bytes large, at most %d are supported", len(program), comMaxSize)
+	}
+
+	context := &Context{}
+	err := writePSP(context, pspSegment, memoryTopSegment, commandTail)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, b := range program {
+		context.WriteMemory8(PhysicalAddress(int16(pspSegment), comLoadOffset)+i, b)
+	}
+
+	for _, register := range []RegisterName{CS, DS, ES, SS} {
+		context.SetRegister(register, int16(pspSegment))
+	}
+	context.InstructionPointer = comLoadOffset
+	// a zero word on the stack makes a near return jump to the int 20h at the start of the PSP
+	context.SetRegister(SP, -2)
+	context.WriteMemory16(PhysicalAddress(int16(pspSegment), -2), 0)
+	return context, nil
+}
+
+// DOS implements the most common services of INT 20h and INT 21h with console I/O through Stdin and Stdout
+type DOS struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+
+	Terminated bool
+	ExitCode   byte
+}
+
+func (d *DOS) Install(context *Context) {
+	context.InterruptHandlers[0x20] = d.terminateInterrupt
+	context.InterruptHandlers[0x21] = d.serviceInterrupt
+}
+
+func (d *DOS) terminate(context *Context, exitCode byte) {
+	d.Terminated = true
+	d.ExitCode = exitCode
+	// with interrupts disabled nothing resumes the terminated program
+	context.Halted = true
+	context.SetFlag(Flag_Interrupt, false)
+}
+
+func (d *DOS) terminateInterrupt(context *Context, number byte) error {
+	d.terminate(context, 0)
+	return nil
+}
+
+func (d *DOS) readCharacter(context *Context) (byte, error) {
+	buffer := []byte{0}
+	read, err := context.readInput(d.Stdin, buffer)
+	if read == 1 {
+		return buffer[0], nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		// end of input reads as Ctrl-Z, like on DOS
+		return 0x1a, nil
+	}
+	return 0, err
+}
+
+func (d *DOS) writeCharacter(character byte) error {
+	_, err := d.Stdout.Write([]byte{character})
+	return err
+}
+
+func (d *DOS) serviceInterrupt(context *Context, number byte) error {
+	function := byte(context.GetRegister(AH))
+	switch function {
+	case 0x00:
+		d.terminate(context, 0)
+	case 0x01, 0x07, 0x08:
+		// read character, with echo for function 01h
+		character, err := d.readCharacter(context)
+		if err != nil {
+			return err
+		}
+		context.SetRegister(AL, int16(character))
+		if function == 0x01 {
+			return d.writeCharacter(character)
+		}
+	case 0x02:
+		return d.writeCharacter(byte(context.GetRegister(DL)))
+	case 0x09:
+		// print string terminated by '$'
+		address := PhysicalAddress(context.GetRegister(DS), context.GetRegister(DX))
+		output := []byte{}
+		for i := 0; i < 0x10000; i++ {
+			character := byte(context.readData(context.GetRegister(DS), address+i, false))
+			if character == '$' {
+				break
+			}
+			output = append(output, character)
+		}
+		_, err := d.Stdout.Write(output)
+		return err
+	case 0x25:
+		// set interrupt vector AL to DS:DX
+		vector := int(byte(context.GetRegister(AL))) * 4
+		context.writeData(0, vector, uint16(context.GetRegister(DX)), true)
+		context.writeData(0, vector+2, uint16(context.GetRegister(DS)), true)
+	case 0x30:
+		context.SetRegister(AX, dosMajorVersion)
+		context.SetRegister(BX, 0)
+		context.SetRegister(CX, 0)
+	case 0x35:
+		// get interrupt vector AL into ES:BX
+		vector := int(byte(context.GetRegister(AL))) * 4
+		context.SetRegister(BX, int16(context.readData(0, vector, true)))
+		context.SetRegister(ES, int16(context.readData(0, vector+2, true)))
+	case 0x3f, 0x40:
+		return d.handleIO(context, function == 0x40)
+	case 0x4c:
+		d.terminate(context, byte(context.GetRegister(AL)))
+	default:
+		return fmt.Errorf("DOS function %02xh is not implemented", function)
+	}
+	return nil
+}
+
+// handleIO reads from or writes to a file handle, only the standard handles are supported
+func (d *DOS) handleIO(context *Context, write bool) error {
+	const errorInvalidHandle = 6
+
+	handle := context.GetRegister(BX)
+	if (write && handle != 1 && handle != 2) || (!write && handle != 0) {
+		context.SetRegister(AX, errorInvalidHandle)
+		context.SetFlag(Flag_Carry, true)
+		return nil
+	}
+
+	address := PhysicalAddress(context.GetRegister(DS), context.GetRegister(DX))
+	count := int(uint16(context.GetRegister(CX)))
+	if write {
+		buffer := make([]byte, count)
+		for i := range buffer {
+			buffer[i] = byte(context.readData(context.GetRegister(DS), address+i, false))
+		}
+		_, err := d.Stdout.Write(buffer)
+		if err != nil {
+			return err
+		}
+	} else {
+		buffer := make([]byte, count)
+		read, err := context.readInput(d.Stdin, buffer)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		for i := 0; i < read; i++ {
+			context.writeData(context.GetRegister(DS), address+i, uint16(buffer[i]), false)
+		}
+		count = read
+	}
+
+	context.SetRegister(AX, int16(count))
+	context.SetFlag(Flag_Carry, false)
+	return nil
+}
@@ -0,0 +1,81 @@
+package simulator8086
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runUntilHalted(t *testing.T, context *Context) {
+	for i := 0; i < 1000 && !context.Halted; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.True(t, context.Halted)
+}
+
+func TestLoadAndRunCOM(t *testing.T) {
+	program := []byte{
+		0xba, 0x16, 0x01, // mov dx, msg
+		0xb4, 0x09, // mov ah, 9
+		0xcd, 0x21, // int 21h
+		0xb4, 0x01, // mov ah, 1
+		0xcd, 0x21, // int 21h
+		0x88, 0xc2, // mov dl, al
+		0xb4, 0x02, // mov ah, 2
+		0xcd, 0x21, // int 21h
+		0xb8, 0x05, 0x4c, // mov ax, 4c05h
+		0xcd, 0x21, // int 21h
+		'H', 'i', ' ', '$',
+	}
+	context, err := LoadCOM(program, 0x1000, " -v")
+	require.NoError(t, err)
+	require.Equal(t, int16(0x1000), context.GetRegister(CS))
+	require.Equal(t, int16(0x1000), context.GetRegister(SS))
+	require.Equal(t, int16(-2), context.GetRegister(SP))
+	require.Equal(t, int16(0x100), context.InstructionPointer)
+	require.Equal(t, []byte{0xcd, 0x20}, context.Memory[0x10000:0x10002])
+	require.Equal(t, []byte{3, ' ', '-', 'v', '\r'}, context.Memory[0x10080:0x10085])
+
+	stdout := &bytes.Buffer{}
+	dos := &DOS{Stdin: strings.NewReader("x"), Stdout: stdout}
+	dos.Install(context)
+	runUntilHalted(t, context)
+
+	require.Equal(t, "Hi xx", stdout.String())
+	require.True(t, dos.Terminated)
+	require.Equal(t, byte(5), dos.ExitCode)
+}
+
+func TestCOMReturnTerminates(t *testing.T) {
+	program := []byte{
+		0xbb, 0x01, 0x00, // mov bx, 1
+		0xb9, 0x02, 0x00, // mov cx, 2
+		0xba, 0x10, 0x01, // mov dx, msg
+		0xb4, 0x40, // mov ah, 40h
+		0xcd, 0x21, // int 21h
+		0xc3, // ret
+		0x90, 0x90,
+		'o', 'k',
+	}
+	context, err := LoadCOM(program, 0x2000, "")
+	require.NoError(t, err)
+
+	stdout := &bytes.Buffer{}
+	dos := &DOS{Stdin: strings.NewReader(""), Stdout: stdout}
+	dos.Install(context)
+	runUntilHalted(t, context)
+
+	require.Equal(t, "ok", stdout.String())
+	require.Equal(t, int16(2), context.GetRegister(AX))
+	require.Equal(t, byte(0), dos.ExitCode)
+
+	// the program fills the segment after the PSP
+	context, err = LoadCOM(make([]byte, 0xff00), 0x2000, "")
+	require.NoError(t, err)
+	require.Equal(t, int16(-2), context.GetRegister(SP))
+	_, err = LoadCOM(make([]byte, 0xff01), 0x2000, "")
+	require.EqualError(t, err, "COM program is 65281 bytes large, at most 65280 are supported")
+}
@@ -0,0 +1,216 @@
+package simulator8086
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// encodedFields are the fields of an instruction taken from its operands
+type encodedFields struct {
+	reg          byte
+	segment      byte
+	escape       byte
+	mod          byte
+	rm           byte
+	displacement int16
+	count        bool
+	value        int16
+	farValue     int16
+	label        int
+}
+
+// place moves value into the bits of mask
+func place(value byte, mask byte) byte {
+	return (value << bits.TrailingZeros8(mask|0x80)) & mask
+}
+
+func registerIndex(name RegisterName, wide bool) (byte, bool) {
+	w := 0
+	if wide {
+		w = 1
+	}
+	for i, register := range registerTable[w] {
+		if register == name {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
+
+func addressModRM(calculation AddressCalculation) (byte, byte, bool) {
+	for mod, row := range addressCalculationTable {
+		for rm, calculationType := range row {
+			if calculationType == calculation.Type {
+				return byte(mod), byte(rm), true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// operationWide tells the width of the operands w applies to, DX and immediates have their own
+func operationWide(encoding *instructionEncoding, locations [2]DataLocation, wide bool) bool {
+	for i, kind := range encoding.operands {
+		location := locations[i]
+		switch kind {
+		case operandRegisterMemory, operandRegister, operandAccumulator, operandAddress:
+			if location.Type == DL_Register {
+				_, registerWide := getPositionAndWide(location.RegisterName)
+				return registerWide
+			}
+			if location.Type == DL_Memory {
+				return location.Wide
+			}
+		}
+	}
+	return wide
+}
+
+func (f *encodedFields) set(kind operandKind, location DataLocation, wide bool) bool {
+	if location.Type == DL_Invalid {
+		return kind == operandNone
+	}
+	ok := false
+	switch kind {
+	case operandRegisterMemory, operandFarPointer:
+		if location.Type == DL_Register {
+			f.mod = 0b11
+			f.rm, ok = registerIndex(location.RegisterName, wide)
+		} else if location.Type == DL_Memory {
+			f.mod, f.rm, ok = addressModRM(location.AddressCalculation)
+			f.displacement = location.AddressCalculation.Displacement
+		}
+	case operandRegister:
+		if location.Type == DL_Register {
+			f.reg, ok = registerIndex(location.RegisterName, wide)
+		}
+	case operandSegment:
+		for i, register := range segmentRegisterTable {
+			if location.Type == DL_Register && location.RegisterName == register {
+				f.segment, ok = byte(i), true
+			}
+		}
+	case operandAccumulator:
+		index, isRegister := registerIndex(location.RegisterName, wide)
+		ok = location.Type == DL_Register && isRegister && index == 0
+	case operandDX:
+		ok = location.Type == DL_Register && location.RegisterName == DX
+	case operandData, operandData8, operandData16, operandPort:
+		f.value, ok = location.ImmediateValue, location.Type == DL_Immediate
+	case operandAddress:
+		f.value = location.AddressCalculation.Displacement
+		ok = location.Type == DL_Memory && location.AddressCalculation.Type == ACT_DirectAddress
+	case operandCount:
+		f.count = location.Type == DL_Register && location.RegisterName == CL
+		ok = f.count || (location.Type == DL_Immediate && location.ImmediateValue == 1)
+	case operandLabel8, operandLabel16:
+		f.label, ok = location.LabelPosition, location.Type == DL_Label
+	case operandFar:
+		f.value, f.farValue, ok = location.ImmediateValue, location.Segment, location.Type == DL_FarAddress
+	case operandEscape:
+		f.escape = byte(location.ImmediateValue)
+		ok = location.Type == DL_Immediate && location.ImmediateValue >= 0 && location.ImmediateValue < 64
+	}
+	return ok
+}
+
+func appendWord(content []byte, value int16) []byte {
+	return append(content, byte(value), byte(uint16(value)>>8))
+}
+
+func fitsInByte(value int) bool {
+	return value >= -128 && value <= 127
+}
+
+// EncodeInstruction is the inverse of DecodeInstruction, by the same row of instructions.txt.
+// Like nasm it prefers the sign extended form of immediates that fit into a byte, so decoding the
+// result gives the same instruction but not always the same bytes.
+func EncodeInstruction(instruction Instruction) ([]byte, error) {
+	if instruction.Type <= IT_Invalid || int(instruction.Type) >= len(instructionEncodings) {
+		return nil, fmt.Errorf("invalid instruction type %d", instruction.Type)
+	}
+	encoding := &instructionEncodings[instruction.Type]
+
+	first := encoding.opcode
+	locations := [2]DataLocation{instruction.Destination, instruction.Source}
+	if encoding.d != 0 && instruction.Source.Type == DL_Memory {
+		first |= encoding.d
+		locations[0], locations[1] = locations[1], locations[0]
+	}
+	wide := encoding.w == 0 || operationWide(encoding, locations, instruction.Wide)
+	if wide {
+		first |= encoding.w
+	}
+
+	fields := encodedFields{}
+	immediate := operandNone
+	for i, kind := range encoding.operands {
+		if !fields.set(kind, locations[i], wide) {
+			return nil, fmt.Errorf("operands of %s do not match its encoding", instruction.Type.Name())
+		}
+		switch kind {
+		case operandData, operandData8, operandData16, operandPort, operandAddress, operandLabel8, operandLabel16, operandFar:
+			immediate = kind
+		}
+	}
+
+	first |= place(fields.reg, encoding.register) | place(fields.segment, encoding.segment) | place(fields.escape>>3, encoding.escape)
+	if fields.count {
+		first |= encoding.v
+	}
+	// rep is encoded as repe
+	first |= encoding.z
+	shortData := !wide || (encoding.s != 0 && fitsInByte(int(fields.value)))
+	if immediate == operandData && wide && shortData {
+		first |= encoding.s
+	}
+	content := []byte{first}
+
+	if encoding.modrm != modrmNone {
+		reg := byte(0)
+		switch encoding.modrm {
+		case modrmRegister:
+			reg = fields.reg
+		case modrmSegment:
+			reg = fields.segment
+		case modrmFixed:
+			reg = encoding.modrmReg
+		case modrmEscape:
+			reg = fields.escape & 0b111
+		}
+		content = append(content, fields.mod<<6|reg<<3|fields.rm)
+		if fields.mod == 0b01 {
+			content = append(content, byte(fields.displacement))
+		} else if fields.mod == 0b10 || (fields.mod == 0b00 && fields.rm == 0b110) {
+			content = appendWord(content, fields.displacement)
+		}
+	}
+	if encoding.hasSecondByte {
+		content = append(content, encoding.secondByte)
+	}
+
+	switch immediate {
+	case operandData:
+		if shortData {
+			content = append(content, byte(fields.value))
+		} else {
+			content = appendWord(content, fields.value)
+		}
+	case operandData8, operandPort:
+		content = append(content, byte(fields.value))
+	case operandData16, operandAddress:
+		content = appendWord(content, fields.value)
+	case operandLabel8:
+		offset := fields.label - (len(content) + 1)
+		if !fitsInByte(offset) {
+			return nil, fmt.Errorf("%s to $%+d is out of range", instruction.Type.Name(), fields.label)
+		}
+		content = append(content, byte(offset))
+	case operandLabel16:
+		content = appendWord(content, int16(fields.label-(len(content)+2)))
+	case operandFar:
+		content = appendWord(content, fields.value)
+		content = appendWord(content, fields.farValue)
+	}
+	return content, nil
+}
@@ -0,0 +1,59 @@
+package simulator8086
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeInstruction(t *testing.T) {
+	instructions, err := Disassemble(benchmarkProgram)
+	require.NoError(t, err)
+
+	content := []byte{}
+	for _, instruction := range instructions {
+		encoded, err := EncodeInstruction(instruction)
+		require.NoError(t, err, instruction.String())
+		content = append(content, encoded...)
+	}
+	require.Equal(t, benchmarkProgram, content)
+}
+
+func TestEncodeDecodedInstructions(t *testing.T) {
+	for first := 0; first < 256; first++ {
+		for second := 0; second < 256; second++ {
+			content := []byte{byte(first), byte(second), 0x12, 0x84, 0x56, 0x78}
+			instruction, err := DecodeInstruction(content)
+			if err != nil {
+				continue
+			}
+			encoded, err := EncodeInstruction(instruction)
+			require.NoError(t, err, "% x", content)
+			decoded, err := DecodeInstruction(append(encoded, 0, 0, 0, 0, 0, 0))
+			require.NoError(t, err, "% x", content)
+			require.Equal(t, instruction.String(), decoded.String(), "% x", content)
+		}
+	}
+}
+
+func TestEncodeEscape(t *testing.T) {
+	for _, content := range [][]byte{{0xd8, 0x07}, {0xd9, 0x86, 0x34, 0x12}, {0xdf, 0xf9}} {
+		instruction, err := DecodeInstruction(content)
+		require.NoError(t, err)
+		encoded, err := EncodeInstruction(instruction)
+		require.NoError(t, err)
+		require.Equal(t, content, encoded)
+	}
+}
+
+func TestEncodeInstructionErrors(t *testing.T) {
+	_, err := EncodeInstruction(Instruction{Type: IT_JNE, Destination: DataLocation{Type: DL_Label, LabelPosition: 200}})
+	require.EqualError(t, err, "jne to $+200 is out of range")
+
+	_, err = EncodeInstruction(Instruction{
+		Type:        IT_MovImToReg,
+		Destination: DataLocation{Type: DL_Memory, AddressCalculation: AddressCalculation{Type: ACT_BX}},
+		Source:      DataLocation{Type: DL_Immediate, ImmediateValue: 1},
+	})
+	require.EqualError(t, err, "operands of mov do not match its encoding")
+}
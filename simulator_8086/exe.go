@@ -0,0 +1,170 @@
+package simulator8086
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	mzHeaderSize   = 0x1c
+	mzPageSize     = 512
+	paragraphSize  = 16
+	pspParagraphs  = 0x10
+	relocationSize = 4
+)
+
+// MZHeader is the header of a DOS executable, sizes are in paragraphs unless noted otherwise
+type MZHeader struct {
+	// bytes used in the last 512 byte page, 0 means the whole page
+	LastPageSize     uint16
+	Pages            uint16
+	RelocationCount  uint16
+	HeaderParagraphs uint16
+	MinAlloc         uint16
+	MaxAlloc         uint16
+	InitialSS        uint16
+	InitialSP        uint16
+	Checksum         uint16
+	InitialIP        uint16
+	InitialCS        uint16
+	RelocationOffset uint16
+	OverlayNumber    uint16
+}
+
+// MZRelocation is the location of a segment word in the load image that is relative to the load segment
+type MZRelocation struct {
+	Offset  uint16
+	Segment uint16
+}
+
+// ParseMZHeader parses and validates the header of an executable, file is the whole file
+func ParseMZHeader(file []byte) (MZHeader, error) {
+	header := MZHeader{}
+	if len(file) < mzHeaderSize {
+		return header, fmt.Errorf("file is %d bytes large, too small for an MZ header", len(file))
+	}
+	if !(file[0] == 'M' && file[1] == 'Z') && !(file[0] == 'Z' && file[1] == 'M') {
+		return header, fmt.Errorf("invalid signature %q, expected \"MZ\"", file[0:2])
+	}
+
+	words := make([]uint16, (mzHeaderSize-2)/2)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint16(file[2+i*2:])
+	}
+	header = MZHeader{
+		LastPageSize:     words[0],
+		Pages:            words[1],
+		RelocationCount:  words[2],
+		HeaderParagraphs: words[3],
+		MinAlloc:         words[4],
+		MaxAlloc:         words[5],
+		InitialSS:        words[6],
+		InitialSP:        words[7],
+		Checksum:         words[8],
+		InitialIP:        words[9],
+		InitialCS:        words[10],
+		RelocationOffset: words[11],
+		OverlayNumber:    words[12],
+	}
+
+	if header.LastPageSize >= mzPageSize {
+		return header, fmt.Errorf("invalid size of the last page %d", header.LastPageSize)
+	}
+	if header.Pages == 0 {
+		return header, fmt.Errorf("executable has no pages")
+	}
+	if header.FileSize() > len(file) {
+		return header, fmt.Errorf("header declares %d bytes but the file is %d bytes large", header.FileSize(), len(file))
+	}
+	if header.HeaderSize() < mzHeaderSize || header.HeaderSize() > header.FileSize() {
+		return header, fmt.Errorf("invalid header size of %d paragraphs", header.HeaderParagraphs)
+	}
+	relocationsEnd := int(header.RelocationOffset) + int(header.RelocationCount)*relocationSize
+	if header.RelocationCount > 0 && (header.RelocationOffset < mzHeaderSize || relocationsEnd > header.HeaderSize()) {
+		return header, fmt.Errorf("relocation table at 0x%x with %d entries is outside of the header", header.RelocationOffset, header.RelocationCount)
+	}
+	if header.MinAlloc > header.MaxAlloc {
+		return header, fmt.Errorf("minimum allocation of %d paragraphs exceeds the maximum of %d", header.MinAlloc, header.MaxAlloc)
+	}
+	return header, nil
+}
+
+// FileSize is the number of bytes of the file that belong to the executable, including the header
+func (h MZHeader) FileSize() int {
+	size := int(h.Pages) * mzPageSize
+	if h.LastPageSize != 0 {
+		size -= mzPageSize - int(h.LastPageSize)
+	}
+	return size
+}
+
+func (h MZHeader) HeaderSize() int {
+	return int(h.HeaderParagraphs) * paragraphSize
+}
+
+// ImageSize is the number of bytes loaded into memory
+func (h MZHeader) ImageSize() int {
+	return h.FileSize() - h.HeaderSize()
+}
+
+func (h MZHeader) Relocations(file []byte) []MZRelocation {
+	relocations := make([]MZRelocation, h.RelocationCount)
+	for i := range relocations {
+		entry := file[int(h.RelocationOffset)+i*relocationSize:]
+		relocations[i] = MZRelocation{
+			Offset:  binary.LittleEndian.Uint16(entry),
+			Segment: binary.LittleEndian.Uint16(entry[2:]),
+		}
+	}
+	return relocations
+}
+
+// LoadEXE creates a context with the program segment prefix at pspSegment:0000 and the load image in the paragraphs after it.
+// As much memory as requested by the header is allocated, up to the end of conventional memory.
+func LoadEXE(file []byte, pspSegment uint16, commandTail string) (*Context, error) {
+	header, err := ParseMZHeader(file)
+	if err != nil {
+		return nil, err
+	}
+
+	loadSegment := int(pspSegment) + pspParagraphs
+	imageParagraphs := (header.ImageSize() + paragraphSize - 1) / paragraphSize
+	available := memoryTopSegment - loadSegment - imageParagraphs
+	if available < int(header.MinAlloc) {
+		return nil, fmt.Errorf("executable needs %d paragraphs but only %d are available at segment 0x%04x",
+			imageParagraphs+int(header.MinAlloc), memoryTopSegment-loadSegment, loadSegment)
+	}
+	extra := int(header.MaxAlloc)
+	if extra > available {
+		extra = available
+	}
+
+	context := &Context{}
+	err = writePSP(context, pspSegment, uint16(loadSegment+imageParagraphs+extra), commandTail)
+	if err != nil {
+		return nil, err
+	}
+
+	image := file[header.HeaderSize():header.FileSize()]
+	loadAddress := loadSegment * paragraphSize
+	for i, b := range image {
+		context.WriteMemory8(loadAddress+i, b)
+	}
+
+	for _, relocation := range header.Relocations(file) {
+		offset := int(relocation.Segment)*paragraphSize + int(relocation.Offset)
+		if offset+2 > len(image) {
+			return nil, fmt.Errorf("relocation %04x:%04x is outside of the load image", relocation.Segment, relocation.Offset)
+		}
+		address := loadAddress + offset
+		context.WriteMemory16(address, context.ReadMemory16(address)+uint16(loadSegment))
+	}
+
+	context.SetRegister(DS, int16(pspSegment))
+	context.SetRegister(ES, int16(pspSegment))
+	context.SetRegister(SS, int16(uint16(loadSegment)+header.InitialSS))
+	context.SetRegister(SP, int16(header.InitialSP))
+	context.SetRegister(CS, int16(uint16(loadSegment)+header.InitialCS))
+	context.InstructionPointer = int16(header.InitialIP)
+	return context, nil
+}
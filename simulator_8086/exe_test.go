@@ -0,0 +1,94 @@
+package simulator8086
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildEXE creates an executable with a two paragraph header, code at 0000:0000 and the stack segment at 0002
+func buildEXE(code []byte, relocations []MZRelocation, minAlloc uint16, maxAlloc uint16) []byte {
+	header := make([]byte, 0x20)
+	file := append(header, code...)
+	for len(file)%paragraphSize != 0 {
+		file = append(file, 0)
+	}
+	words := []uint16{
+		uint16(len(file) % mzPageSize), uint16((len(file) + mzPageSize - 1) / mzPageSize),
+		uint16(len(relocations)), 2, minAlloc, maxAlloc,
+		uint16(len(code)+paragraphSize-1) / paragraphSize, 0x100, 0, 0, 0, mzHeaderSize, 0,
+	}
+	file[0], file[1] = 'M', 'Z'
+	for i, word := range words {
+		binary.LittleEndian.PutUint16(file[2+i*2:], word)
+	}
+	for i, relocation := range relocations {
+		binary.LittleEndian.PutUint16(file[mzHeaderSize+i*4:], relocation.Offset)
+		binary.LittleEndian.PutUint16(file[mzHeaderSize+i*4+2:], relocation.Segment)
+	}
+	return file
+}
+
+func TestLoadAndRunEXE(t *testing.T) {
+	code := []byte{
+		0xb8, 0x00, 0x00, // mov ax, seg data (relocated)
+		0x8e, 0xd8, // mov ds, ax
+		0xba, 0x10, 0x00, // mov dx, msg
+		0xb4, 0x09, // mov ah, 9
+		0xcd, 0x21, // int 21h
+		0xb8, 0x00, 0x4c, // mov ax, 4c00h
+		0xcd, 0x21, // int 21h
+	}
+	code = append(code, make([]byte, 0x20-len(code))...)
+	code = append(code, []byte("exe$")...)
+	// the data segment is the second paragraph of the image, msg is at data:0010
+	binary.LittleEndian.PutUint16(code[1:], 1)
+	file := buildEXE(code, []MZRelocation{{Offset: 1, Segment: 0}}, 0x10, 0x20)
+
+	context, err := LoadEXE(file, 0x1000, "")
+	require.NoError(t, err)
+	require.Equal(t, int16(0x1010), context.GetRegister(CS))
+	require.Equal(t, int16(0), context.InstructionPointer)
+	require.Equal(t, int16(0x1000), context.GetRegister(DS))
+	require.Equal(t, int16(0x1000), context.GetRegister(ES))
+	require.Equal(t, int16(0x1013), context.GetRegister(SS))
+	require.Equal(t, int16(0x100), context.GetRegister(SP))
+	require.Equal(t, uint16(0x1011), context.ReadMemory16(0x10101))
+	// psp, 3 paragraphs of image and 0x20 extra paragraphs
+	require.Equal(t, uint16(0x1010+3+0x20), context.ReadMemory16(0x10002))
+
+	stdout := &bytes.Buffer{}
+	dos := &DOS{Stdin: strings.NewReader(""), Stdout: stdout}
+	dos.Install(context)
+	runUntilHalted(t, context)
+	require.Equal(t, "exe", stdout.String())
+}
+
+func TestMalformedEXE(t *testing.T) {
+	valid := buildEXE([]byte{0xc3}, nil, 0, 0xffff)
+	_, err := LoadEXE(valid, 0x1000, "")
+	require.NoError(t, err)
+
+	corrupt := func(offset int, value uint16) []byte {
+		file := append([]byte{}, valid...)
+		binary.LittleEndian.PutUint16(file[offset:], value)
+		return file
+	}
+	tests := map[string][]byte{
+		"truncated":         valid[:10],
+		"signature":         corrupt(0, 0x1234),
+		"last page":         corrupt(2, mzPageSize),
+		"pages":             corrupt(4, 10),
+		"relocation count":  corrupt(6, 10),
+		"header size":       corrupt(8, 0x100),
+		"min alloc":         corrupt(0x0a, 0xf000),
+		"relocation target": buildEXE([]byte{0xc3}, []MZRelocation{{Offset: 0, Segment: 0x10}}, 0, 0),
+	}
+	for name, file := range tests {
+		_, err := LoadEXE(file, 0x1000, "")
+		require.Error(t, err, name)
+	}
+}
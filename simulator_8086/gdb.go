@@ -0,0 +1,417 @@
+package simulator8086
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// gdb with "set architecture i8086" uses the i386 register layout, every register is sent as 32-bit little endian value
+var gdbRegisterTable = []RegisterName{AX, CX, DX, BX, SP, BP, SI, DI}
+
+const (
+	gdbRegisterIP    = 8
+	gdbRegisterFlags = 9
+	gdbRegisterCount = 16
+)
+
+var gdbSegmentRegisterTable = []RegisterName{CS, SS, DS, ES}
+
+type gdbPacket struct {
+	Data      string
+	Valid     bool
+	Interrupt bool
+}
+
+// GDBServer implements the GDB Remote Serial Protocol for a Context.
+// Memory and breakpoint addresses are interpreted as physical addresses.
+type GDBServer struct {
+	Context     *Context
+	Breakpoints map[int]bool
+
+	writer  io.Writer
+	packets chan gdbPacket
+	// packets received while the target was running, handled after it stops
+	pending []gdbPacket
+	noAck   bool
+}
+
+func NewGDBServer(context *Context) *GDBServer {
+	return &GDBServer{
+		Context:     context,
+		Breakpoints: make(map[int]bool),
+	}
+}
+
+func ListenAndServeGDB(address string, context *Context) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	conn, err := listener.Accept()
+	listener.Close()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return NewGDBServer(context).Serve(conn)
+}
+
+func gdbChecksum(data string) byte {
+	checksum := byte(0)
+	for i := 0; i < len(data); i++ {
+		checksum += data[i]
+	}
+	return checksum
+}
+
+func (s *GDBServer) readPackets(reader *bufio.Reader) {
+	defer close(s.packets)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+
+		// acknowledgements ('+' and '-') are ignored, we never retransmit
+		if b == 0x03 {
+			s.packets <- gdbPacket{Interrupt: true}
+			continue
+		}
+		if b != '$' {
+			continue
+		}
+
+		data, err := reader.ReadString('#')
+		if err != nil {
+			return
+		}
+		data = strings.TrimSuffix(data, "#")
+
+		checksum := make([]byte, 2)
+		_, err = io.ReadFull(reader, checksum)
+		if err != nil {
+			return
+		}
+
+		expected, err := strconv.ParseUint(string(checksum), 16, 8)
+		s.packets <- gdbPacket{
+			Data:  data,
+			Valid: err == nil && byte(expected) == gdbChecksum(data),
+		}
+	}
+}
+
+func (s *GDBServer) send(data string) error {
+	_, err := fmt.Fprintf(s.writer, "$%s#%02x", data, gdbChecksum(data))
+	return err
+}
+
+func (s *GDBServer) nextPacket() (gdbPacket, bool) {
+	if len(s.pending) != 0 {
+		packet := s.pending[0]
+		s.pending = s.pending[1:]
+		return packet, true
+	}
+	packet, ok := <-s.packets
+	return packet, ok
+}
+
+func (s *GDBServer) Serve(conn io.ReadWriter) error {
+	s.writer = conn
+	s.packets = make(chan gdbPacket, 16)
+	go s.readPackets(bufio.NewReader(conn))
+
+	for {
+		packet, ok := s.nextPacket()
+		if !ok {
+			break
+		}
+		if packet.Interrupt {
+			// the target is already stopped
+			continue
+		}
+
+		if !packet.Valid {
+			_, err := s.writer.Write([]byte("-"))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !s.noAck {
+			_, err := s.writer.Write([]byte("+"))
+			if err != nil {
+				return err
+			}
+		}
+
+		if packet.Data == "k" {
+			return nil
+		}
+
+		err := s.send(s.handlePacket(packet.Data))
+		if err != nil {
+			return err
+		}
+
+		if packet.Data == "QStartNoAckMode" {
+			s.noAck = true
+		}
+		if strings.HasPrefix(packet.Data, "D") {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *GDBServer) handlePacket(data string) string {
+	switch {
+	case data == "?":
+		return "S05"
+	case data == "g":
+		result := ""
+		for i := 0; i < gdbRegisterCount; i++ {
+			result += s.encodeRegister(i)
+		}
+		return result
+	case strings.HasPrefix(data, "G"):
+		values, err := hex.DecodeString(data[1:])
+		if err != nil {
+			return "E01"
+		}
+		s.beginEdit()
+		for i := 0; i < gdbRegisterCount && (i+1)*4 <= len(values); i++ {
+			s.writeRegister(i, binary.LittleEndian.Uint32(values[i*4:]))
+		}
+		return "OK"
+	case strings.HasPrefix(data, "p"):
+		index, err := strconv.ParseUint(data[1:], 16, 32)
+		if err != nil {
+			return "E01"
+		}
+		return s.encodeRegister(int(index))
+	case strings.HasPrefix(data, "P"):
+		indexStr, valueStr, found := strings.Cut(data[1:], "=")
+		index, err := strconv.ParseUint(indexStr, 16, 32)
+		if !found || err != nil {
+			return "E01"
+		}
+		value, err := hex.DecodeString(valueStr)
+		if err != nil || len(value) < 4 {
+			return "E01"
+		}
+		s.beginEdit()
+		s.writeRegister(int(index), binary.LittleEndian.Uint32(value))
+		return "OK"
+	case strings.HasPrefix(data, "m"):
+		address, length, err := parseGDBAddressAndLength(data[1:])
+		if err != nil {
+			return "E01"
+		}
+		memory := make([]byte, length)
+		for i := range memory {
+			memory[i] = s.Context.ReadMemory8(address + i)
+		}
+		return hex.EncodeToString(memory)
+	case strings.HasPrefix(data, "M"):
+		location, valueStr, found := strings.Cut(data[1:], ":")
+		if !found {
+			return "E01"
+		}
+		address, length, err := parseGDBAddressAndLength(location)
+		if err != nil {
+			return "E01"
+		}
+		values, err := hex.DecodeString(valueStr)
+		if err != nil || len(values) != length {
+			return "E01"
+		}
+		s.beginEdit()
+		for i, value := range values {
+			s.Context.WriteMemory8(address+i, value)
+		}
+		return "OK"
+	case strings.HasPrefix(data, "Z0,") || strings.HasPrefix(data, "z0,"):
+		addressStr, _, _ := strings.Cut(data[3:], ",")
+		address, err := strconv.ParseUint(addressStr, 16, 32)
+		if err != nil {
+			return "E01"
+		}
+		if data[0] == 'Z' {
+			s.Breakpoints[int(address)] = true
+		} else {
+			delete(s.Breakpoints, int(address))
+		}
+		return "OK"
+	case data == "bs" || data == "bc":
+		return s.reverse(data == "bs")
+	case strings.HasPrefix(data, "s") || strings.HasPrefix(data, "c"):
+		if len(data) > 1 && !s.setResumeAddress(data[1:]) {
+			return "E01"
+		}
+		return s.resume(data[0] == 's')
+	case strings.HasPrefix(data, "qSupported"):
+		if s.Context.History != nil {
+			return "PacketSize=4000;QStartNoAckMode+;ReverseStep+;ReverseContinue+"
+		}
+		return "PacketSize=4000;QStartNoAckMode+"
+	case data == "QStartNoAckMode":
+		return "OK"
+	case data == "qAttached":
+		return "1"
+	case data == "qC":
+		return "QC1"
+	case data == "qfThreadInfo":
+		return "m1"
+	case data == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(data, "H"), strings.HasPrefix(data, "T"), strings.HasPrefix(data, "D"):
+		return "OK"
+	}
+
+	// an empty response tells gdb that the packet is not supported
+	return ""
+}
+
+func parseGDBAddressAndLength(data string) (int, int, error) {
+	addressStr, lengthStr, found := strings.Cut(data, ",")
+	if !found {
+		return 0, 0, fmt.Errorf("missing length in %q", data)
+	}
+	address, err := strconv.ParseUint(addressStr, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := strconv.ParseUint(lengthStr, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	if length > MemorySize {
+		return 0, 0, fmt.Errorf("length %d is larger than the memory", length)
+	}
+	return int(address), int(length), nil
+}
+
+// beginEdit makes the changes of the debugger a step of the history, so stepping back does not
+// undo them together with the last instruction
+func (s *GDBServer) beginEdit() {
+	if s.Context.History != nil {
+		s.Context.History.beginStep(s.Context)
+	}
+}
+
+func (s *GDBServer) readRegister(index int) uint32 {
+	switch {
+	case index < len(gdbRegisterTable):
+		return uint32(uint16(s.Context.GetRegister(gdbRegisterTable[index])))
+	case index == gdbRegisterIP:
+		return uint32(uint16(s.Context.InstructionPointer))
+	case index == gdbRegisterFlags:
+		return uint32(s.Context.FlagsWord())
+	case index-gdbRegisterFlags-1 < len(gdbSegmentRegisterTable):
+		return uint32(uint16(s.Context.GetRegister(gdbSegmentRegisterTable[index-gdbRegisterFlags-1])))
+	}
+
+	// fs and gs do not exist on the 8086
+	return 0
+}
+
+func (s *GDBServer) writeRegister(index int, value uint32) {
+	switch {
+	case index < len(gdbRegisterTable):
+		s.Context.SetRegister(gdbRegisterTable[index], int16(value))
+	case index == gdbRegisterIP:
+		s.Context.InstructionPointer = int16(value)
+	case index == gdbRegisterFlags:
+		s.Context.SetFlagsWord(uint16(value))
+	case index-gdbRegisterFlags-1 < len(gdbSegmentRegisterTable):
+		s.Context.SetRegister(gdbSegmentRegisterTable[index-gdbRegisterFlags-1], int16(value))
+	}
+}
+
+func (s *GDBServer) encodeRegister(index int) string {
+	value := [4]byte{}
+	binary.LittleEndian.PutUint32(value[:], s.readRegister(index))
+	return hex.EncodeToString(value[:])
+}
+
+// setResumeAddress moves IP to the physical address of an s or c packet, it has to be in the code segment
+func (s *GDBServer) setResumeAddress(data string) bool {
+	address, err := strconv.ParseUint(data, 16, 32)
+	if err != nil {
+		return false
+	}
+	offset := int(address) - PhysicalAddress(s.Context.GetRegister(CS), 0)
+	if offset < 0 || offset > 0xffff {
+		return false
+	}
+	s.Context.InstructionPointer = int16(offset)
+	return true
+}
+
+// interrupted tells whether gdb sent ^C or disconnected, other packets are kept for the main loop
+func (s *GDBServer) interrupted() bool {
+	for {
+		select {
+		case packet, ok := <-s.packets:
+			if !ok {
+				return true
+			}
+			if packet.Interrupt {
+				return true
+			}
+			s.pending = append(s.pending, packet)
+		default:
+			return false
+		}
+	}
+}
+
+func (s *GDBServer) resume(singleStep bool) string {
+	for i := 1; ; i++ {
+		_, err := Step(s.Context)
+		if err != nil {
+			// report everything the simulator can not execute as illegal instruction
+			return "S04"
+		}
+
+		if s.Context.haltedForever() {
+			return "W00"
+		}
+
+		if singleStep || s.Breakpoints[s.Context.InstructionAddress()] {
+			return "S05"
+		}
+
+		if i%1024 == 0 && s.interrupted() {
+			return "S02"
+		}
+	}
+}
+
+func (s *GDBServer) reverse(singleStep bool) string {
+	if s.Context.History == nil {
+		return "E01"
+	}
+
+	var err error
+	if singleStep {
+		err = s.Context.History.StepBack(s.Context)
+	} else {
+		err = s.Context.History.RunBackward(s.Context, s.Breakpoints)
+	}
+	if err != nil {
+		return "T05replaylog:begin;"
+	}
+	return "S05"
+}
@@ -0,0 +1,181 @@
+package simulator8086
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sendGDBPacket(t *testing.T, conn net.Conn, reader *bufio.Reader, data string) string {
+	_, err := fmt.Fprintf(conn, "$%s#%02x", data, gdbChecksum(data))
+	require.NoError(t, err)
+
+	ack, err := reader.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('+'), ack)
+	return readGDBResponse(t, conn, reader)
+}
+
+func readGDBResponse(t *testing.T, conn net.Conn, reader *bufio.Reader) string {
+	start, err := reader.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('$'), start)
+
+	response, err := reader.ReadString('#')
+	require.NoError(t, err)
+	response = response[:len(response)-1]
+
+	checksum := make([]byte, 2)
+	_, err = reader.Read(checksum)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%02x", gdbChecksum(response)), string(checksum))
+
+	_, err = conn.Write([]byte("+"))
+	require.NoError(t, err)
+	return response
+}
+
+func TestGDBServer(t *testing.T) {
+	context := &Context{}
+	program := []byte{
+		0xb8, 0x01, 0x00, // mov ax, 1
+		0xbb, 0x02, 0x00, // mov bx, 2
+		0x89, 0x1e, 0x00, 0x01, // mov [256], bx
+	}
+	copy(context.Memory[:], program)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	done := make(chan error)
+	go func() {
+		done <- NewGDBServer(context).Serve(server)
+		server.Close()
+	}()
+	reader := bufio.NewReader(client)
+
+	require.Equal(t, "S05", sendGDBPacket(t, client, reader, "?"))
+	require.Equal(t, "b80100", sendGDBPacket(t, client, reader, "m0,3"))
+	require.Equal(t, "E01", sendGDBPacket(t, client, reader, "m0,ffffffff"))
+
+	require.Equal(t, "S05", sendGDBPacket(t, client, reader, "s"))
+	require.Equal(t, "01000000", sendGDBPacket(t, client, reader, "p0"))
+	require.Equal(t, "03000000", sendGDBPacket(t, client, reader, "p8"))
+
+	require.Equal(t, "OK", sendGDBPacket(t, client, reader, "Z0,6,1"))
+	require.Equal(t, "S05", sendGDBPacket(t, client, reader, "c"))
+	require.Equal(t, int16(2), context.GetRegister(BX))
+	require.Equal(t, int16(6), context.InstructionPointer)
+	require.Equal(t, "OK", sendGDBPacket(t, client, reader, "z0,6,1"))
+
+	// s and c resume at the given address
+	require.Equal(t, "S05", sendGDBPacket(t, client, reader, "s3"))
+	require.Equal(t, int16(6), context.InstructionPointer)
+	require.Equal(t, "E01", sendGDBPacket(t, client, reader, "s20000"))
+
+	require.Equal(t, "OK", sendGDBPacket(t, client, reader, "P3=34120000"))
+	require.Equal(t, "S05", sendGDBPacket(t, client, reader, "s"))
+	require.Equal(t, "3412", sendGDBPacket(t, client, reader, "m100,2"))
+
+	require.Equal(t, "OK", sendGDBPacket(t, client, reader, "M100,2:cdab"))
+	require.Equal(t, uint16(0xabcd), context.ReadMemory16(0x100))
+
+	registers := sendGDBPacket(t, client, reader, "g")
+	require.Len(t, registers, gdbRegisterCount*8)
+	require.Equal(t, "34120000", registers[3*8:4*8])
+
+	require.Equal(t, "", sendGDBPacket(t, client, reader, "vMustReplyEmpty"))
+	require.Equal(t, "OK", sendGDBPacket(t, client, reader, "D"))
+	require.NoError(t, <-done)
+}
+
+func TestGDBServerPacketsWhileRunning(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[:], []byte{
+		0xb8, 0x01, 0x00, // mov ax, 1
+		0xeb, 0xfe, // jmp $
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		NewGDBServer(context).Serve(server)
+		server.Close()
+	}()
+	reader := bufio.NewReader(client)
+
+	_, err := fmt.Fprintf(client, "$c#%02x", gdbChecksum("c"))
+	require.NoError(t, err)
+	ack, err := reader.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('+'), ack)
+
+	// a packet sent while the target runs is answered after ^C stops it
+	_, err = fmt.Fprintf(client, "$p0#%02x", gdbChecksum("p0"))
+	require.NoError(t, err)
+	_, err = client.Write([]byte{0x03})
+	require.NoError(t, err)
+	require.Equal(t, "S02", readGDBResponse(t, client, reader))
+
+	ack, err = reader.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('+'), ack)
+	require.Equal(t, "01000000", readGDBResponse(t, client, reader))
+}
+
+func TestGDBServerDisconnectWhileRunning(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[:], []byte{
+		0xeb, 0xfe, // jmp $
+	})
+
+	client, server := net.Pipe()
+	done := make(chan error)
+	go func() {
+		done <- NewGDBServer(context).Serve(server)
+		server.Close()
+	}()
+	reader := bufio.NewReader(client)
+
+	_, err := fmt.Fprintf(client, "$c#%02x", gdbChecksum("c"))
+	require.NoError(t, err)
+	ack, err := reader.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('+'), ack)
+
+	// the program loops forever, closing the connection stops it
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the server kept running after the debugger disconnected")
+	}
+}
+
+func TestGDBServerWritesAreHistorySteps(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[:], []byte{
+		0xc7, 0x06, 0x00, 0x01, 0x34, 0x12, // mov word [256], 4660
+	})
+	history := EnableHistory(context, HistoryConfig{})
+	_, err := Step(context)
+	require.NoError(t, err)
+
+	server := NewGDBServer(context)
+	require.Equal(t, "OK", server.handlePacket("M100,2:cdab"))
+	require.Equal(t, "OK", server.handlePacket("P0=05000000"))
+	require.Equal(t, uint64(3), history.Position())
+
+	// stepping back undoes the changes of the debugger before the instruction
+	require.Equal(t, "S05", server.handlePacket("bs"))
+	require.Equal(t, int16(0), context.GetRegister(AX))
+	require.Equal(t, uint16(0xabcd), context.ReadMemory16(0x100))
+	require.Equal(t, "S05", server.handlePacket("bs"))
+	require.Equal(t, uint16(0x1234), context.ReadMemory16(0x100))
+	require.Equal(t, int16(6), context.InstructionPointer)
+	require.Equal(t, "S05", server.handlePacket("bs"))
+	require.Equal(t, uint16(0), context.ReadMemory16(0x100))
+}
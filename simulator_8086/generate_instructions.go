@@ -0,0 +1,380 @@
+//go:build ignore
+
+// generate_instructions turns the encoding table of instructions.txt into instructions_gen.go,
+// run it with go generate
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var operandKinds = map[string]string{
+	"rm":     "operandRegisterMemory",
+	"farptr": "operandFarPointer",
+	"reg":    "operandRegister",
+	"sr":     "operandSegment",
+	"acc":    "operandAccumulator",
+	"dx":     "operandDX",
+	"data":   "operandData",
+	"data8":  "operandData8",
+	"data16": "operandData16",
+	"port":   "operandPort",
+	"addr":   "operandAddress",
+	"count":  "operandCount",
+	"ip8":    "operandLabel8",
+	"ip16":   "operandLabel16",
+	"far":    "operandFar",
+	"esc":    "operandEscape",
+}
+
+// the fields after the first byte that are read as the immediate of an operand
+var immediates = map[string]bool{
+	"data": true, "data8": true, "data16": true, "port": true, "addr": true, "ip8": true, "ip16": true, "far": true,
+}
+
+type encoding struct {
+	line     int
+	name     string
+	mnemonic string
+	// a blank line precedes the row in the file
+	groupStart bool
+
+	opcode, opcodeMask     byte
+	d, w, s, v, z          byte
+	register, segment      byte
+	escape                 byte
+	modrm                  string
+	modrmReg, modrmRegMask byte
+	secondByte             byte
+	hasSecondByte          bool
+	immediate              string
+	operands               []string
+}
+
+func parseFirstByte(e *encoding, pattern string) error {
+	if len(pattern) != 8 {
+		return fmt.Errorf("first byte %q is not 8 bits", pattern)
+	}
+	for i := 0; i < 8; i++ {
+		bit := byte(1) << (7 - i)
+		switch {
+		case strings.HasPrefix(pattern[i:], "reg"):
+			e.register = bit | bit>>1 | bit>>2
+			i += 2
+		case strings.HasPrefix(pattern[i:], "esc"):
+			e.escape = bit | bit>>1 | bit>>2
+			i += 2
+		case strings.HasPrefix(pattern[i:], "sr"):
+			e.segment = bit | bit>>1
+			i++
+		case pattern[i] == '0' || pattern[i] == '1':
+			e.opcodeMask |= bit
+			if pattern[i] == '1' {
+				e.opcode |= bit
+			}
+		case pattern[i] == 'x':
+		case pattern[i] == 'd':
+			e.d = bit
+		case pattern[i] == 'w':
+			e.w = bit
+		case pattern[i] == 's':
+			e.s = bit
+		case pattern[i] == 'v':
+			e.v = bit
+		case pattern[i] == 'z':
+			e.z = bit
+		default:
+			return fmt.Errorf("unknown field in first byte %q", pattern)
+		}
+	}
+	return nil
+}
+
+func parseModRM(e *encoding, fields []string) error {
+	if len(fields) < 4 || fields[2] != "rm" || fields[3] != "disp" {
+		return fmt.Errorf("expected mod ??? rm disp")
+	}
+	switch reg := fields[1]; {
+	case reg == "reg":
+		e.modrm = "modrmRegister"
+	case reg == "0sr":
+		e.modrm = "modrmSegment"
+		e.modrmRegMask = 0b100
+	case reg == "esc":
+		e.modrm = "modrmEscape"
+	default:
+		value, err := strconv.ParseUint(reg, 2, 8)
+		if err != nil || len(reg) != 3 {
+			return fmt.Errorf("unknown reg field %q", reg)
+		}
+		e.modrm = "modrmFixed"
+		e.modrmReg = byte(value)
+		e.modrmRegMask = 0b111
+	}
+	return nil
+}
+
+func parseLine(e *encoding, text string) error {
+	encodingText, operandsText, _ := strings.Cut(text, "|")
+	fields := strings.Fields(encodingText)
+	if len(fields) < 3 {
+		return fmt.Errorf("expected type, mnemonic and encoding")
+	}
+	e.name, e.mnemonic = fields[0], fields[1]
+	if err := parseFirstByte(e, fields[2]); err != nil {
+		return err
+	}
+
+	rest := fields[3:]
+	if len(rest) > 0 && rest[0] == "mod" {
+		if err := parseModRM(e, rest); err != nil {
+			return err
+		}
+		rest = rest[4:]
+	} else if len(rest) > 0 && len(rest[0]) == 8 && strings.Trim(rest[0], "01") == "" {
+		value, _ := strconv.ParseUint(rest[0], 2, 8)
+		e.secondByte, e.hasSecondByte = byte(value), true
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		if !immediates[rest[0]] {
+			return fmt.Errorf("unknown field %q", rest[0])
+		}
+		e.immediate = rest[0]
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected fields %v", rest)
+	}
+
+	for _, operand := range strings.Split(operandsText, ",") {
+		operand = strings.TrimSpace(operand)
+		if operand == "" {
+			continue
+		}
+		if _, ok := operandKinds[operand]; !ok {
+			return fmt.Errorf("unknown operand %q", operand)
+		}
+		e.operands = append(e.operands, operand)
+	}
+	return validate(e)
+}
+
+// validate checks that every field of the encoding is an operand and the other way around
+func validate(e *encoding) error {
+	if len(e.operands) > 2 {
+		return fmt.Errorf("more than 2 operands")
+	}
+	uses := map[string]bool{}
+	for _, operand := range e.operands {
+		uses[operand] = true
+	}
+	fields := []struct {
+		name    string
+		encoded bool
+		used    bool
+	}{
+		{"reg", e.register != 0 || e.modrm == "modrmRegister", uses["reg"]},
+		{"sr", e.segment != 0 || e.modrm == "modrmSegment", uses["sr"]},
+		{"rm", e.modrm != "", uses["rm"] || uses["farptr"]},
+		{"v", e.v != 0, uses["count"]},
+		{"esc", e.escape != 0 || e.modrm == "modrmEscape", uses["esc"]},
+	}
+	for _, field := range fields {
+		if field.encoded != field.used {
+			return fmt.Errorf("the %s field of the encoding and the operands do not match", field.name)
+		}
+	}
+	if (e.escape != 0) != (e.modrm == "modrmEscape") {
+		return fmt.Errorf("esc needs bits in the first byte and the reg field")
+	}
+	if e.d != 0 && len(e.operands) != 2 {
+		return fmt.Errorf("d needs 2 operands")
+	}
+	if e.s != 0 && !uses["data"] {
+		return fmt.Errorf("s needs a data operand")
+	}
+	if e.immediate != "" && !uses[e.immediate] {
+		return fmt.Errorf("%s is not an operand", e.immediate)
+	}
+	for operand := range uses {
+		if immediates[operand] && operand != e.immediate {
+			return fmt.Errorf("%s is not encoded", operand)
+		}
+	}
+	return nil
+}
+
+func parse(path string) ([]*encoding, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	encodings := []*encoding{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	blank := false
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if text == "" {
+			blank = len(encodings) != 0
+			continue
+		}
+		e := &encoding{line: line, groupStart: blank}
+		blank = false
+		if err := parseLine(e, text); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		encodings = append(encodings, e)
+	}
+	return encodings, scanner.Err()
+}
+
+type opcodeEntry struct {
+	instructionType string
+	group           *[8]string
+}
+
+func buildOpcodeTable(encodings []*encoding) ([256]opcodeEntry, error) {
+	table := [256]opcodeEntry{}
+	for _, e := range encodings {
+		for b := 0; b < 256; b++ {
+			if byte(b)&e.opcodeMask != e.opcode {
+				continue
+			}
+			entry := &table[b]
+			if e.modrmRegMask == 0 {
+				if entry.instructionType != "" || entry.group != nil {
+					return table, fmt.Errorf("line %d: opcode 0x%02x is already used", e.line, b)
+				}
+				entry.instructionType = e.name
+				continue
+			}
+			if entry.instructionType != "" {
+				return table, fmt.Errorf("line %d: opcode 0x%02x is already used", e.line, b)
+			}
+			if entry.group == nil {
+				entry.group = &[8]string{}
+			}
+			for reg := byte(0); reg < 8; reg++ {
+				if reg&e.modrmRegMask != e.modrmReg {
+					continue
+				}
+				if entry.group[reg] != "" {
+					return table, fmt.Errorf("line %d: reg %d of opcode 0x%02x is already used", e.line, reg, b)
+				}
+				entry.group[reg] = e.name
+			}
+		}
+	}
+	return table, nil
+}
+
+func writeEncoding(out *bytes.Buffer, e *encoding) {
+	fields := []string{fmt.Sprintf("opcode: 0x%02x, opcodeMask: 0x%02x", e.opcode, e.opcodeMask)}
+	for _, bit := range []struct {
+		name  string
+		value byte
+	}{{"d", e.d}, {"w", e.w}, {"s", e.s}, {"v", e.v}, {"z", e.z}, {"register", e.register}, {"segment", e.segment}, {"escape", e.escape}} {
+		if bit.value != 0 {
+			fields = append(fields, fmt.Sprintf("%s: 0x%02x", bit.name, bit.value))
+		}
+	}
+	if e.modrm != "" {
+		fields = append(fields, "modrm: "+e.modrm)
+	}
+	if e.modrmRegMask != 0 {
+		fields = append(fields, fmt.Sprintf("modrmReg: 0b%03b, modrmRegMask: 0b%03b", e.modrmReg, e.modrmRegMask))
+	}
+	if e.hasSecondByte {
+		fields = append(fields, fmt.Sprintf("secondByte: 0x%02x, hasSecondByte: true", e.secondByte))
+	}
+	if len(e.operands) != 0 {
+		kinds := []string{}
+		for _, operand := range e.operands {
+			kinds = append(kinds, operandKinds[operand])
+		}
+		fields = append(fields, fmt.Sprintf("operands: [2]operandKind{%s}", strings.Join(kinds, ", ")))
+	}
+	fmt.Fprintf(out, "\tIT_%s: {%s},\n", e.name, strings.Join(fields, ", "))
+}
+
+func generate(encodings []*encoding) ([]byte, error) {
+	table, err := buildOpcodeTable(encodings)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "// Code generated by generate_instructions.go from instructions.txt; DO NOT EDIT.\n\n")
+	fmt.Fprintf(out, "package simulator8086\n\n")
+
+	fmt.Fprintf(out, "type InstructionType int\n\nconst (\n\tIT_Invalid InstructionType = iota\n")
+	for _, e := range encodings {
+		if e.groupStart {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "\tIT_%s\n", e.name)
+	}
+	fmt.Fprintf(out, ")\n\n")
+
+	fmt.Fprintf(out, "var instructionNames = [...]string{\n\tIT_Invalid: \"unknown\",\n")
+	for _, e := range encodings {
+		fmt.Fprintf(out, "\tIT_%s: %q,\n", e.name, e.mnemonic)
+	}
+	fmt.Fprintf(out, "}\n\n")
+
+	fmt.Fprintf(out, "var instructionEncodings = [...]instructionEncoding{\n\tIT_Invalid: {},\n")
+	for _, e := range encodings {
+		writeEncoding(out, e)
+	}
+	fmt.Fprintf(out, "}\n\n")
+
+	fmt.Fprintf(out, "var opcodeTable = [256]opcodeEntry{\n")
+	for b, entry := range table {
+		switch {
+		case entry.group != nil:
+			types := []string{}
+			for reg, name := range entry.group {
+				if name != "" {
+					types = append(types, fmt.Sprintf("%d: IT_%s", reg, name))
+				}
+			}
+			sort.Strings(types)
+			fmt.Fprintf(out, "\t0x%02x: {group: &[8]InstructionType{%s}},\n", b, strings.Join(types, ", "))
+		case entry.instructionType != "":
+			fmt.Fprintf(out, "\t0x%02x: {instructionType: IT_%s},\n", b, entry.instructionType)
+		}
+	}
+	fmt.Fprintf(out, "}\n")
+	return format.Source(out.Bytes())
+}
+
+func main() {
+	encodings, err := parse("instructions.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := generate(encodings)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = os.WriteFile("instructions_gen.go", source, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
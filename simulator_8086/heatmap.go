@@ -0,0 +1,226 @@
+package simulator8086
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+)
+
+// SegmentAccesses counts the bytes read and written through a value of a segment register
+type SegmentAccesses struct {
+	Segment uint16
+	Reads   uint64
+	Writes  uint64
+}
+
+// ReadWriteRatio returns the reads per write, +Inf if there are no writes
+func (s SegmentAccesses) ReadWriteRatio() float64 {
+	if s.Writes == 0 {
+		return math.Inf(1)
+	}
+	return float64(s.Reads) / float64(s.Writes)
+}
+
+type MemoryStatistics struct {
+	Reads  uint64
+	Writes uint64
+	// the number of distinct bytes and of distinct 16 and 64 byte blocks that were accessed
+	WorkingSet   int
+	WorkingSet16 int
+	WorkingSet64 int
+	// the largest number of bytes below the stack pointer of the first instruction
+	StackHighWater int
+	// the segments accesses were made through, in ascending order
+	Segments []SegmentAccesses
+}
+
+// MemoryHeatmap counts the data reads and writes of the program per physical byte, a wide access counts
+// for both bytes. Instruction fetches are not counted.
+type MemoryHeatmap struct {
+	BaseObserver
+	Reads  []uint64
+	Writes []uint64
+
+	segments       map[uint16]*SegmentAccesses
+	stackStarted   bool
+	stackSegment   int16
+	stackTop       int16
+	stackHighWater int
+}
+
+func NewMemoryHeatmap() *MemoryHeatmap {
+	return &MemoryHeatmap{
+		Reads:    make([]uint64, MemorySize),
+		Writes:   make([]uint64, MemorySize),
+		segments: map[uint16]*SegmentAccesses{},
+	}
+}
+
+func (h *MemoryHeatmap) BeforeInstruction(context *Context, address int, instruction Instruction) {
+	if !h.stackStarted {
+		h.stackStarted = true
+		h.stackSegment = context.GetRegister(SS)
+		h.stackTop = context.GetRegister(SP)
+	}
+}
+
+func (h *MemoryHeatmap) AfterInstruction(context *Context, address int, instruction Instruction) {
+	// a program that switches to its own stack starts a new one
+	if context.GetRegister(SS) != h.stackSegment {
+		h.stackSegment = context.GetRegister(SS)
+		h.stackTop = context.GetRegister(SP)
+		return
+	}
+	depth := int(h.stackTop - context.GetRegister(SP))
+	if depth > h.stackHighWater {
+		h.stackHighWater = depth
+	}
+}
+
+func (h *MemoryHeatmap) segment(segment int16) *SegmentAccesses {
+	accesses, ok := h.segments[uint16(segment)]
+	if !ok {
+		accesses = &SegmentAccesses{Segment: uint16(segment)}
+		h.segments[uint16(segment)] = accesses
+	}
+	return accesses
+}
+
+func (h *MemoryHeatmap) MemoryRead(context *Context, segment int16, address int, size int, value uint16) {
+	for i := 0; i < size; i++ {
+		h.Reads[(address+i)%MemorySize]++
+	}
+	h.segment(segment).Reads += uint64(size)
+}
+
+func (h *MemoryHeatmap) MemoryWrite(context *Context, segment int16, address int, size int, value uint16) {
+	for i := 0; i < size; i++ {
+		h.Writes[(address+i)%MemorySize]++
+	}
+	h.segment(segment).Writes += uint64(size)
+}
+
+// Blocks returns the reads and writes per block of blockSize bytes
+func (h *MemoryHeatmap) Blocks(blockSize int) ([]uint64, []uint64) {
+	reads := make([]uint64, MemorySize/blockSize)
+	writes := make([]uint64, MemorySize/blockSize)
+	for address := range h.Reads {
+		reads[address/blockSize] += h.Reads[address]
+		writes[address/blockSize] += h.Writes[address]
+	}
+	return reads, writes
+}
+
+func (h *MemoryHeatmap) Statistics() MemoryStatistics {
+	statistics := MemoryStatistics{StackHighWater: h.stackHighWater}
+	blocks16 := map[int]bool{}
+	blocks64 := map[int]bool{}
+	for address := range h.Reads {
+		reads, writes := h.Reads[address], h.Writes[address]
+		if reads == 0 && writes == 0 {
+			continue
+		}
+		statistics.Reads += reads
+		statistics.Writes += writes
+		statistics.WorkingSet++
+		blocks16[address/16] = true
+		blocks64[address/64] = true
+	}
+	statistics.WorkingSet16 = len(blocks16)
+	statistics.WorkingSet64 = len(blocks64)
+
+	for _, segment := range h.segments {
+		statistics.Segments = append(statistics.Segments, *segment)
+	}
+	sort.Slice(statistics.Segments, func(i, j int) bool {
+		return statistics.Segments[i].Segment < statistics.Segments[j].Segment
+	})
+	return statistics
+}
+
+func (h *MemoryHeatmap) WriteStatistics(writer io.Writer) error {
+	statistics := h.Statistics()
+	buffered := bufio.NewWriter(writer)
+	fmt.Fprintf(buffered, "reads: %d\nwrites: %d\n", statistics.Reads, statistics.Writes)
+	fmt.Fprintf(buffered, "working set: %d bytes, %d blocks of 16 bytes, %d blocks of 64 bytes\n",
+		statistics.WorkingSet, statistics.WorkingSet16, statistics.WorkingSet64)
+	fmt.Fprintf(buffered, "stack high-water mark: %d bytes\n", statistics.StackHighWater)
+	for _, segment := range statistics.Segments {
+		fmt.Fprintf(buffered, "segment 0x%04x: %d reads, %d writes, %.2f reads per write\n",
+			segment.Segment, segment.Reads, segment.Writes, segment.ReadWriteRatio())
+	}
+	return buffered.Flush()
+}
+
+// WriteCSV writes the reads and writes of every block of blockSize bytes that was accessed
+func (h *MemoryHeatmap) WriteCSV(writer io.Writer, blockSize int) error {
+	if blockSize <= 0 || MemorySize%blockSize != 0 {
+		return fmt.Errorf("invalid block size %d", blockSize)
+	}
+	reads, writes := h.Blocks(blockSize)
+	buffered := bufio.NewWriter(writer)
+	fmt.Fprintln(buffered, "address,reads,writes")
+	for block := range reads {
+		if reads[block] != 0 || writes[block] != 0 {
+			fmt.Fprintf(buffered, "0x%05x,%d,%d\n", block*blockSize, reads[block], writes[block])
+		}
+	}
+	return buffered.Flush()
+}
+
+// heatColor maps 0 to black and 1 to white through blue, red and yellow
+func heatColor(t float64) color.NRGBA {
+	stops := []color.NRGBA{
+		{0x00, 0x00, 0x00, 0xff},
+		{0x00, 0x00, 0xff, 0xff},
+		{0xff, 0x00, 0x00, 0xff},
+		{0xff, 0xff, 0x00, 0xff},
+		{0xff, 0xff, 0xff, 0xff},
+	}
+	position := t * float64(len(stops)-1)
+	index := int(position)
+	if index >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	fraction := position - float64(index)
+	from, to := stops[index], stops[index+1]
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*fraction)
+	}
+	return color.NRGBA{mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B), 0xff}
+}
+
+// Image draws the accesses of every block of blockSize bytes as one pixel, width blocks per line.
+// The scale is logarithmic, so rarely accessed memory is still visible next to hot loops.
+func (h *MemoryHeatmap) Image(blockSize int, width int) (image.Image, error) {
+	if blockSize <= 0 || MemorySize%blockSize != 0 {
+		return nil, fmt.Errorf("invalid block size %d", blockSize)
+	}
+	blocks := MemorySize / blockSize
+	if width <= 0 || blocks%width != 0 {
+		return nil, fmt.Errorf("invalid width %d for %d blocks", width, blocks)
+	}
+
+	reads, writes := h.Blocks(blockSize)
+	maximum := uint64(0)
+	for block := range reads {
+		if reads[block]+writes[block] > maximum {
+			maximum = reads[block] + writes[block]
+		}
+	}
+
+	result := image.NewNRGBA(image.Rect(0, 0, width, blocks/width))
+	scale := math.Log1p(float64(maximum))
+	for block := range reads {
+		t := 0.0
+		if maximum != 0 {
+			t = math.Log1p(float64(reads[block]+writes[block])) / scale
+		}
+		result.SetNRGBA(block%width, block/width, heatColor(t))
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryHeatmap(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[0x100:], []byte{
+		0x50,             // push ax
+		0x50,             // push ax
+		0x58,             // pop ax
+		0x58,             // pop ax
+		0xa3, 0x20, 0x00, // mov [32], ax
+		0xa1, 0x20, 0x00, // mov ax, [32]
+		0xa0, 0x21, 0x00, // mov al, [33]
+		0xf4, // hlt
+	})
+	context.InstructionPointer = 0x100
+	// the stack and the data are in the same 64 KB of memory, but not in the same segment
+	context.SetRegister(SS, 0x1000)
+	context.SetRegister(SP, 0x100)
+	context.SetRegister(DS, 0x1001)
+
+	heatmap := NewMemoryHeatmap()
+	context.AddObserver(heatmap)
+	Run(gocontext.Background(), context, RunOptions{})
+
+	require.Equal(t, uint64(1), heatmap.Writes[0x100fc])
+	require.Equal(t, uint64(2), heatmap.Reads[0x10031])
+	require.Equal(t, MemoryStatistics{
+		Reads:          7,
+		Writes:         6,
+		WorkingSet:     6,
+		WorkingSet16:   2,
+		WorkingSet64:   2,
+		StackHighWater: 4,
+		Segments: []SegmentAccesses{
+			{Segment: 0x1000, Reads: 4, Writes: 4},
+			{Segment: 0x1001, Reads: 3, Writes: 2},
+		},
+	}, heatmap.Statistics())
+	require.Equal(t, 1.5, heatmap.Statistics().Segments[1].ReadWriteRatio())
+
+	reads, writes := heatmap.Blocks(64)
+	require.Equal(t, uint64(4), reads[0x100c0/64])
+	require.Equal(t, uint64(2), writes[0x10000/64])
+
+	text := &strings.Builder{}
+	require.NoError(t, heatmap.WriteStatistics(text))
+	require.Contains(t, text.String(), "segment 0x1001: 3 reads, 2 writes, 1.50 reads per write\n")
+
+	csv := &strings.Builder{}
+	require.NoError(t, heatmap.WriteCSV(csv, 16))
+	require.Equal(t, "address,reads,writes\n0x10030,3,2\n0x100f0,4,4\n", csv.String())
+
+	img, err := heatmap.Image(16, 256)
+	require.NoError(t, err)
+	require.Equal(t, 256, img.Bounds().Dy())
+	require.Equal(t, color.NRGBA{0xff, 0xff, 0xff, 0xff}, img.At(0x0f, 0x10))
+	require.Equal(t, color.NRGBA{0x00, 0x00, 0x00, 0xff}, img.At(0, 0))
+
+	_, err = heatmap.Image(16, 100)
+	require.Error(t, err)
+}
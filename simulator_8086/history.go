@@ -0,0 +1,292 @@
+package simulator8086
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrHistoryExhausted = errors.New("no more history available")
+
+const (
+	historyEntryTypeRegister = iota
+	historyEntryTypeMemory
+)
+
+// approximate sizes in bytes, used to stay within the memory budget
+const (
+	historyEntrySize    = 8
+	historyStepSize     = 40
+	historySnapshotSize = MemorySize + 64
+)
+
+type historyEntry struct {
+	Type     uint8
+	OldValue byte
+	Address  int32
+}
+
+type historyStep struct {
+	InstructionPointer int16
+	Flags              uint16
+	Halted             bool
+	Clocks             uint64
+	Instructions       uint64
+	FirstEntry         int
+}
+
+type historySnapshot struct {
+	Position           uint64
+	Registers          [24]byte
+	Flags              [9]bool
+	InstructionPointer int16
+	Halted             bool
+	Clocks             uint64
+	Instructions       uint64
+	Memory             []byte
+}
+
+type HistoryConfig struct {
+	// MemoryBudget limits the memory in bytes used for the undo journal and the snapshots combined,
+	// up to half of it is used for snapshots, 0 means unlimited
+	MemoryBudget int
+	// SnapshotInterval is the number of instructions between two full snapshots, 0 disables snapshots.
+	// Snapshots only help to go back further than the journal reaches, so the interval should be larger than that.
+	SnapshotInterval uint64
+}
+
+// History records an undo journal of every register, flag and memory write per step, along with the
+// halted state and the clock and instruction counters before it. A step is an instruction, an idle cycle
+// while halted, the entry of a hardware interrupt or a change made by the debugger.
+// State of devices outside of Context.Memory is not recorded.
+type History struct {
+	Config HistoryConfig
+
+	journalStart uint64
+	steps        []historyStep
+	entries      []historyEntry
+	snapshots    []historySnapshot
+}
+
+func EnableHistory(context *Context, config HistoryConfig) *History {
+	history := &History{Config: config}
+	if config.SnapshotInterval != 0 {
+		history.takeSnapshot(context)
+	}
+	context.History = history
+	return history
+}
+
+func (h *History) Position() uint64 {
+	return h.journalStart + uint64(len(h.steps))
+}
+
+func (h *History) OldestPosition() uint64 {
+	if len(h.snapshots) != 0 && h.snapshots[0].Position < h.journalStart {
+		return h.snapshots[0].Position
+	}
+	return h.journalStart
+}
+
+func (h *History) MemoryUsage() int {
+	return len(h.entries)*historyEntrySize + len(h.steps)*historyStepSize + len(h.snapshots)*historySnapshotSize
+}
+
+func (h *History) recordRegister(context *Context, position int, wide bool) {
+	h.entries = append(h.entries, historyEntry{Type: historyEntryTypeRegister, OldValue: context.Registers[position], Address: int32(position)})
+	if wide {
+		h.entries = append(h.entries, historyEntry{Type: historyEntryTypeRegister, OldValue: context.Registers[position+1], Address: int32(position + 1)})
+	}
+}
+
+func (h *History) recordMemory(context *Context, address int) {
+	h.entries = append(h.entries, historyEntry{Type: historyEntryTypeMemory, OldValue: context.Memory[address], Address: int32(address)})
+}
+
+func (h *History) beginStep(context *Context) {
+	if h.Config.SnapshotInterval != 0 && h.Position()%h.Config.SnapshotInterval == 0 && h.Position() != h.lastSnapshotPosition() {
+		h.takeSnapshot(context)
+	}
+
+	h.steps = append(h.steps, historyStep{
+		InstructionPointer: context.InstructionPointer,
+		Flags:              context.FlagsWord(),
+		Halted:             context.Halted,
+		Clocks:             context.Clocks,
+		Instructions:       context.Instructions,
+		FirstEntry:         len(h.entries),
+	})
+
+	if h.Config.MemoryBudget != 0 {
+		h.enforceBudget()
+	}
+}
+
+func (h *History) lastSnapshotPosition() uint64 {
+	if len(h.snapshots) == 0 {
+		return ^uint64(0)
+	}
+	return h.snapshots[len(h.snapshots)-1].Position
+}
+
+func (h *History) takeSnapshot(context *Context) {
+	h.snapshots = append(h.snapshots, historySnapshot{
+		Position:           h.Position(),
+		Registers:          context.Registers,
+		Flags:              context.Flags,
+		InstructionPointer: context.InstructionPointer,
+		Halted:             context.Halted,
+		Clocks:             context.Clocks,
+		Instructions:       context.Instructions,
+		Memory:             append([]byte{}, context.Memory[:]...),
+	})
+}
+
+func (h *History) enforceBudget() {
+	// snapshots may use up to half of the budget, the oldest ones are dropped first
+	for len(h.snapshots) > 1 && len(h.snapshots)*historySnapshotSize > h.Config.MemoryBudget/2 {
+		h.snapshots[0].Memory = nil
+		h.snapshots = h.snapshots[1:]
+	}
+
+	// the current step is always kept
+	for h.MemoryUsage() > h.Config.MemoryBudget && len(h.steps) > 1 {
+		// dropping a quarter of the journal at once keeps the cost of moving the remaining steps low
+		dropCount := (len(h.steps) + 3) / 4
+		if dropCount == len(h.steps) {
+			dropCount--
+		}
+		dropEntries := h.steps[dropCount].FirstEntry
+		h.entries = append(h.entries[:0], h.entries[dropEntries:]...)
+		h.steps = append(h.steps[:0], h.steps[dropCount:]...)
+		for i := range h.steps {
+			h.steps[i].FirstEntry -= dropEntries
+		}
+		h.journalStart += uint64(dropCount)
+	}
+}
+
+func (h *History) dropFutureSnapshots() {
+	for len(h.snapshots) != 0 && h.snapshots[len(h.snapshots)-1].Position > h.Position() {
+		h.snapshots = h.snapshots[:len(h.snapshots)-1]
+	}
+}
+
+func (h *History) undo(context *Context) {
+	step := h.steps[len(h.steps)-1]
+	for i := len(h.entries) - 1; i >= step.FirstEntry; i-- {
+		entry := h.entries[i]
+		if entry.Type == historyEntryTypeRegister {
+			context.Registers[entry.Address] = entry.OldValue
+		} else {
+			context.Memory[entry.Address] = entry.OldValue
+			if context.InstructionCache != nil {
+				context.InstructionCache.invalidate(int(entry.Address))
+			}
+		}
+	}
+	context.InstructionPointer = step.InstructionPointer
+	context.SetFlagsWord(step.Flags)
+	context.Halted = step.Halted
+	context.Clocks = step.Clocks
+	context.Instructions = step.Instructions
+
+	h.entries = h.entries[:step.FirstEntry]
+	h.steps = h.steps[:len(h.steps)-1]
+	h.dropFutureSnapshots()
+}
+
+func (h *History) StepBack(context *Context) error {
+	if len(h.steps) == 0 {
+		return ErrHistoryExhausted
+	}
+
+	h.undo(context)
+	return nil
+}
+
+// RunBackward steps backward until the instruction at a breakpoint is reached
+func (h *History) RunBackward(context *Context, breakpoints map[int]bool) error {
+	for {
+		err := h.StepBack(context)
+		if err != nil {
+			return err
+		}
+
+		if breakpoints[context.InstructionAddress()] {
+			return nil
+		}
+	}
+}
+
+// RunBackToWrite steps backward until right before the last instruction that wrote to the given address
+func (h *History) RunBackToWrite(context *Context, address int) error {
+	for len(h.steps) != 0 {
+		step := h.steps[len(h.steps)-1]
+		wroteAddress := false
+		for _, entry := range h.entries[step.FirstEntry:] {
+			if entry.Type == historyEntryTypeMemory && int(entry.Address) == address {
+				wroteAddress = true
+				break
+			}
+		}
+
+		h.undo(context)
+		if wroteAddress {
+			return nil
+		}
+	}
+
+	return ErrHistoryExhausted
+}
+
+func (h *History) JumpTo(context *Context, position uint64) error {
+	if position < h.journalStart {
+		snapshotIndex := -1
+		for i, snapshot := range h.snapshots {
+			if snapshot.Position <= position {
+				snapshotIndex = i
+			}
+		}
+		if snapshotIndex == -1 {
+			return fmt.Errorf("instruction %d is no longer available, the oldest is %d: %w", position, h.OldestPosition(), ErrHistoryExhausted)
+		}
+
+		snapshot := h.snapshots[snapshotIndex]
+		context.Registers = snapshot.Registers
+		context.Flags = snapshot.Flags
+		context.InstructionPointer = snapshot.InstructionPointer
+		context.Halted = snapshot.Halted
+		context.Clocks = snapshot.Clocks
+		context.Instructions = snapshot.Instructions
+		copy(context.Memory[:], snapshot.Memory)
+		if context.InstructionCache != nil {
+			context.InstructionCache.Clear()
+		}
+
+		h.snapshots = h.snapshots[:snapshotIndex+1]
+		h.steps = h.steps[:0]
+		h.entries = h.entries[:0]
+		h.journalStart = snapshot.Position
+	}
+
+	for h.Position() > position {
+		h.undo(context)
+	}
+
+	for h.Position() < position {
+		start := h.Position()
+		_, err := Step(context)
+		if err != nil {
+			return err
+		}
+		if h.Position() == start {
+			return fmt.Errorf("instruction %d can not be reached, the simulation stopped at %d", position, start)
+		}
+	}
+	// a step that enters a hardware interrupt records two steps
+	for h.Position() > position {
+		h.undo(context)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package simulator8086
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadCounterProgram(context *Context) {
+	program := []byte{
+		0xb9, 0x05, 0x00, // mov cx, 5
+		0x89, 0x0e, 0x00, 0x02, // mov [512], cx
+		0xe2, 0xfa, // loop -6
+	}
+	copy(context.Memory[:], program)
+}
+
+func TestHistoryStepBack(t *testing.T) {
+	context := &Context{}
+	loadCounterProgram(context)
+	history := EnableHistory(context, HistoryConfig{})
+
+	for i := 0; i < 7; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.Equal(t, uint64(7), history.Position())
+	require.Equal(t, int16(2), context.GetRegister(CX))
+	require.Equal(t, uint16(3), context.ReadMemory16(512))
+
+	err := history.StepBack(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(3), context.GetRegister(CX))
+	require.Equal(t, int16(7), context.InstructionPointer)
+
+	err = history.RunBackToWrite(context, 512)
+	require.NoError(t, err)
+	require.Equal(t, int16(3), context.InstructionPointer)
+	require.Equal(t, uint16(4), context.ReadMemory16(512))
+
+	err = history.RunBackward(context, map[int]bool{7: true})
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), history.Position())
+
+	err = history.JumpTo(context, 0)
+	require.NoError(t, err)
+	require.True(t, DiffContexts(&Context{History: history, Memory: context.Memory}, context).Empty())
+
+	err = history.StepBack(context)
+	require.ErrorIs(t, err, ErrHistoryExhausted)
+}
+
+func loadEndlessLoopProgram(context *Context) {
+	program := []byte{
+		0x89, 0x0e, 0x00, 0x02, // mov [512], cx
+		0xe2, 0xfa, // loop -6
+		0xe2, 0xf8, // loop -8
+	}
+	copy(context.Memory[:], program)
+}
+
+func TestHistoryMemoryBudget(t *testing.T) {
+	context := &Context{}
+	loadEndlessLoopProgram(context)
+	history := EnableHistory(context, HistoryConfig{
+		MemoryBudget:     4 * historySnapshotSize,
+		SnapshotInterval: 100000,
+	})
+
+	for i := 0; i < 350000; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.LessOrEqual(t, history.MemoryUsage(), history.Config.MemoryBudget)
+	require.Equal(t, uint64(350000), history.Position())
+	require.Equal(t, uint64(200000), history.OldestPosition())
+
+	// the target is only reachable by restoring a snapshot and replaying from there
+	target := history.OldestPosition() + 1
+	require.Less(t, target, history.journalStart)
+
+	expected := &Context{}
+	loadEndlessLoopProgram(expected)
+	for i := uint64(0); i < target; i++ {
+		_, err := Step(expected)
+		require.NoError(t, err)
+	}
+
+	err := history.JumpTo(context, target)
+	require.NoError(t, err)
+	require.True(t, DiffContexts(expected, context).Empty(), DiffContexts(expected, context).String())
+	require.Equal(t, expected.Instructions, context.Instructions)
+	require.Equal(t, expected.Clocks, context.Clocks)
+
+	err = history.JumpTo(context, history.OldestPosition()-1)
+	require.ErrorIs(t, err, ErrHistoryExhausted)
+}
+
+func TestHistoryStepBackOverHalt(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[:], []byte{
+		0xb8, 0x01, 0x00, // mov ax, 1
+		0xf4, // hlt
+	})
+	history := EnableHistory(context, HistoryConfig{})
+
+	_, err := Step(context)
+	require.NoError(t, err)
+	clocks := context.Clocks
+	for i := 0; i < 3; i++ {
+		_, err = Step(context)
+		require.NoError(t, err)
+	}
+	require.True(t, context.Halted)
+	haltedClocks := context.Clocks
+	require.Equal(t, uint64(4), history.Position())
+
+	// the idle cycles while halted are steps too
+	err = history.StepBack(context)
+	require.NoError(t, err)
+	require.True(t, context.Halted)
+	require.Less(t, context.Clocks, haltedClocks)
+
+	err = history.JumpTo(context, 1)
+	require.NoError(t, err)
+	require.False(t, context.Halted)
+	require.Equal(t, uint64(1), context.Instructions)
+	require.Equal(t, clocks, context.Clocks)
+	require.Equal(t, int16(3), context.InstructionPointer)
+
+	err = history.JumpTo(context, 4)
+	require.NoError(t, err)
+	require.True(t, context.Halted)
+	require.Equal(t, haltedClocks, context.Clocks)
+	require.Equal(t, uint64(2), context.Instructions)
+}
+
+func TestHistoryInterruptEntryStep(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[0x100:], []byte{
+		0xb8, 0x01, 0x00, // mov ax, 1
+	})
+	context.InstructionPointer = 0x100
+	context.SetRegister(SP, 0x1000)
+	context.SetFlag(Flag_Interrupt, true)
+	context.WriteMemory16(8*4, 0x200)
+	pic := NewPIC()
+	require.NoError(t, pic.Attach(context))
+	pic.WritePort(1, 0xfe)
+	pic.RaiseIRQ(0)
+	history := EnableHistory(context, HistoryConfig{})
+
+	_, err := Step(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(0x200), context.InstructionPointer)
+	require.Equal(t, uint64(2), history.Position())
+
+	// undoing the entry of the interrupt keeps the instruction before it
+	err = history.StepBack(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(0x103), context.InstructionPointer)
+	require.Equal(t, int16(0x1000), context.GetRegister(SP))
+	require.Equal(t, uint16(0), context.ReadMemory16(0xffe))
+	require.Equal(t, int16(1), context.GetRegister(AX))
+
+	err = history.StepBack(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(0), context.GetRegister(AX))
+}
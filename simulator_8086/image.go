@@ -0,0 +1,175 @@
+package simulator8086
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type PixelFormat int
+
+const (
+	PF_RGBA8 PixelFormat = iota
+	PF_Palette8
+	// CGA 320x200 graphics mode, 4 pixels per byte and odd lines start 0x2000 bytes after even lines
+	PF_CGA2bpp
+)
+
+const cgaOddLinesOffset = 0x2000
+
+func PixelFormatFromName(name string) (PixelFormat, error) {
+	switch strings.ToLower(name) {
+	case "rgba8":
+		return PF_RGBA8, nil
+	case "palette8":
+		return PF_Palette8, nil
+	case "cga2bpp":
+		return PF_CGA2bpp, nil
+	}
+	return PF_RGBA8, fmt.Errorf("unknown pixel format %q", name)
+}
+
+// cyan, magenta and white on black, the default graphics palette of the CGA
+var CGAPalette = color.Palette{
+	color.RGBA{0x00, 0x00, 0x00, 0xff},
+	color.RGBA{0x55, 0xff, 0xff, 0xff},
+	color.RGBA{0xff, 0x55, 0xff, 0xff},
+	color.RGBA{0xff, 0xff, 0xff, 0xff},
+}
+
+func grayscalePalette() color.Palette {
+	palette := make(color.Palette, 256)
+	for i := range palette {
+		palette[i] = color.Gray{Y: uint8(i)}
+	}
+	return palette
+}
+
+type ImageOptions struct {
+	// physical address of the first pixel
+	Start  int
+	Width  int
+	Height int
+	Format PixelFormat
+	// used for PF_Palette8 and PF_CGA2bpp, defaults to grayscale and CGAPalette respectively.
+	// It needs a color for every value of a pixel, 256 and 4 respectively.
+	Palette color.Palette
+}
+
+func MemoryImage(context *Context, options ImageOptions) (image.Image, error) {
+	if options.Width <= 0 || options.Height <= 0 {
+		return nil, fmt.Errorf("invalid image size %dx%d", options.Width, options.Height)
+	}
+
+	bounds := image.Rect(0, 0, options.Width, options.Height)
+	switch options.Format {
+	case PF_RGBA8:
+		result := image.NewNRGBA(bounds)
+		for i := range result.Pix {
+			result.Pix[i] = context.ReadMemory8(options.Start + i)
+		}
+		return result, nil
+	case PF_Palette8:
+		palette := options.Palette
+		if palette == nil {
+			palette = grayscalePalette()
+		}
+		if len(palette) < 256 {
+			return nil, fmt.Errorf("palette has %d colors, 8 bit pixels need 256", len(palette))
+		}
+		result := image.NewPaletted(bounds, palette)
+		for i := range result.Pix {
+			result.Pix[i] = context.ReadMemory8(options.Start + i)
+		}
+		return result, nil
+	case PF_CGA2bpp:
+		if options.Width%4 != 0 {
+			return nil, fmt.Errorf("width %d is not a multiple of 4 pixels", options.Width)
+		}
+		palette := options.Palette
+		if palette == nil {
+			palette = CGAPalette
+		}
+		if len(palette) < 4 {
+			return nil, fmt.Errorf("palette has %d colors, 2 bit pixels need 4", len(palette))
+		}
+		result := image.NewPaletted(bounds, palette)
+		bytesPerLine := options.Width / 4
+		for y := 0; y < options.Height; y++ {
+			lineAddress := options.Start + (y%2)*cgaOddLinesOffset + (y/2)*bytesPerLine
+			for x := 0; x < options.Width; x++ {
+				b := context.ReadMemory8(lineAddress + x/4)
+				// the leftmost pixel is stored in the most significant bits
+				shift := 6 - (x%4)*2
+				result.SetColorIndex(x, y, (b>>shift)&0b11)
+			}
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("unknown pixel format %d", options.Format)
+}
+
+func WritePNG(writer io.Writer, img image.Image) error {
+	return png.Encode(writer, img)
+}
+
+// WritePPM writes a binary PPM (P6), alpha is discarded
+func WritePPM(writer io.Writer, img image.Image) error {
+	buffered := bufio.NewWriter(writer)
+	bounds := img.Bounds()
+	_, err := fmt.Fprintf(buffered, "P6\n%d %d\n255\n", bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return err
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			_, err = buffered.Write([]byte{pixel.R, pixel.G, pixel.B})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return buffered.Flush()
+}
+
+// ExportImage writes a memory region as PNG or PPM, depending on the file extension
+func ExportImage(context *Context, options ImageOptions, path string) error {
+	img, err := MemoryImage(context, options)
+	if err != nil {
+		return err
+	}
+	return WriteImageFile(img, path)
+}
+
+// WriteImageFile writes an image as PNG or PPM, depending on the file extension
+func WriteImageFile(img image.Image, path string) error {
+	write := WritePNG
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+	case ".ppm":
+		write = WritePPM
+	default:
+		return fmt.Errorf("unknown image file extension of %s, expected .png or .ppm", path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(file, img)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
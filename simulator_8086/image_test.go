@@ -0,0 +1,69 @@
+package simulator8086
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryImageRGBA8(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[0x100:], []byte{
+		0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff,
+		0x00, 0x00, 0xff, 0xff, 0x10, 0x20, 0x30, 0xff,
+	})
+
+	img, err := MemoryImage(context, ImageOptions{Start: 0x100, Width: 2, Height: 2, Format: PF_RGBA8})
+	require.NoError(t, err)
+	require.Equal(t, color.NRGBA{0x00, 0x00, 0xff, 0xff}, img.At(0, 1))
+	require.Equal(t, color.NRGBA{0x10, 0x20, 0x30, 0xff}, img.At(1, 1))
+
+	ppm := &bytes.Buffer{}
+	require.NoError(t, WritePPM(ppm, img))
+	require.Equal(t, "P6\n2 2\n255\n\xff\x00\x00\x00\xff\x00\x00\x00\xff\x10\x20\x30", ppm.String())
+
+	path := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, ExportImage(context, ImageOptions{Start: 0x100, Width: 2, Height: 2, Format: PF_RGBA8}, path))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	require.NoError(t, err)
+	require.Equal(t, img.(*image.NRGBA).Pix, decoded.(*image.RGBA).Pix)
+}
+
+func TestMemoryImagePalettes(t *testing.T) {
+	context := &Context{}
+	context.Memory[0x10] = 0x80
+
+	img, err := MemoryImage(context, ImageOptions{Start: 0x10, Width: 1, Height: 1, Format: PF_Palette8})
+	require.NoError(t, err)
+	require.Equal(t, color.Gray{Y: 0x80}, img.At(0, 0))
+
+	// pixels 1, 2, 3, 0 on the first line and 3, 0, 0, 0 on the second
+	context.Memory[0xb8000] = 0b01101100
+	context.Memory[0xb8000+cgaOddLinesOffset] = 0b11000000
+	img, err = MemoryImage(context, ImageOptions{Start: 0xb8000, Width: 4, Height: 2, Format: PF_CGA2bpp})
+	require.NoError(t, err)
+	require.Equal(t, CGAPalette[1], img.At(0, 0))
+	require.Equal(t, CGAPalette[2], img.At(1, 0))
+	require.Equal(t, CGAPalette[3], img.At(2, 0))
+	require.Equal(t, CGAPalette[0], img.At(3, 0))
+	require.Equal(t, CGAPalette[3], img.At(0, 1))
+	require.Equal(t, CGAPalette[0], img.At(1, 1))
+
+	_, err = MemoryImage(context, ImageOptions{Width: 3, Height: 1, Format: PF_CGA2bpp})
+	require.Error(t, err)
+
+	// a short palette would leave pixel values without a color
+	_, err = MemoryImage(context, ImageOptions{Start: 0x10, Width: 1, Height: 1, Format: PF_Palette8, Palette: CGAPalette})
+	require.EqualError(t, err, "palette has 4 colors, 8 bit pixels need 256")
+	_, err = MemoryImage(context, ImageOptions{Width: 4, Height: 1, Format: PF_CGA2bpp, Palette: CGAPalette[:2]})
+	require.EqualError(t, err, "palette has 2 colors, 2 bit pixels need 4")
+}
@@ -5,7 +5,7 @@ import (
 	"strconv"
 )
 
-type AddressCalculationType int
+type AddressCalculationType uint8
 
 const (
 	ACT_Invalid AddressCalculationType = iota
@@ -46,14 +46,17 @@ type AddressCalculation struct {
 	Displacement int16
 }
 
-type DataLocationType int
+type DataLocationType uint8
 
 const (
+	// the operand is not present
 	DL_Invalid DataLocationType = iota
 	DL_Register
 	DL_Memory
 	DL_Immediate
 	DL_Label
+	// segment:offset of a far call or jump, the offset is stored in ImmediateValue
+	DL_FarAddress
 )
 
 type RegisterName string
@@ -88,26 +91,29 @@ var registerTable = [][]RegisterName{
 	{AX, CX, DX, BX, SP, BP, SI, DI},
 }
 
+// Instruction is a decoded instruction, a value without pointers that can be copied and kept in
+// buffers without allocations
 type Instruction struct {
 	Type        InstructionType
 	SizeInBytes int
 	Wide        bool
-	Destination *DataLocation
-	Source      *DataLocation
+	// operands that the instruction does not have are DL_Invalid
+	Destination DataLocation
+	Source      DataLocation
 }
 
 type DataLocation struct {
-	Type DataLocationType
-
 	RegisterName RegisterName
 
+	LabelPosition int
+
 	AddressCalculation AddressCalculation
 
 	ImmediateValue int16
-	Wide           bool
-
-	LabelPosition int
+	Segment        int16
 
+	Type          DataLocationType
+	Wide          bool
 	AvoidSizeInfo bool
 }
 
@@ -193,14 +199,16 @@ func (d DataLocation) String() string {
 		return result + d.AddressCalculation.String()
 	case DL_Label:
 		return fmt.Sprintf("$%+d", d.LabelPosition)
+	case DL_FarAddress:
+		return fmt.Sprintf("%d:%d", uint16(d.Segment), uint16(d.ImmediateValue))
 	}
 
 	panic("unknown data location")
 }
 
 func (i Instruction) String() string {
-	if i.Source == nil {
-		if i.Destination == nil {
+	if i.Source.Type == DL_Invalid {
+		if i.Destination.Type == DL_Invalid {
 			wide := ""
 			if i.Type.IsStringManipulationInstruction() {
 				if i.Wide {
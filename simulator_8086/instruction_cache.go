@@ -0,0 +1,141 @@
+package simulator8086
+
+// the longest instruction has an opcode, mod reg r/m, a 16-bit displacement and a 16-bit immediate
+const maxInstructionSize = 6
+
+const instructionCachePageSize = 256
+
+type cachedInstruction struct {
+	instruction Instruction
+	valid       bool
+}
+
+type instructionCachePage [instructionCachePageSize]cachedInstruction
+
+type InstructionCacheOptions struct {
+	// decode the rest of the basic block on a miss, up to the next jump, call, return, interrupt or hlt
+	BasicBlocks bool
+}
+
+// InstructionCache keeps decoded instructions by physical address, so Step decodes the instructions
+// of a loop once. Writes through WriteMemory8 and WriteMemory16 drop the instructions they change,
+// so self-modifying code keeps working. Instructions in device memory are not cached.
+// Clear has to be called after writing Context.Memory directly or changing the memory map.
+type InstructionCache struct {
+	Options InstructionCacheOptions
+	Hits    uint64
+	Misses  uint64
+	// number of cached instructions dropped by writes
+	Invalidations uint64
+
+	pages [MemorySize / instructionCachePageSize]*instructionCachePage
+}
+
+func NewInstructionCache(options InstructionCacheOptions) *InstructionCache {
+	return &InstructionCache{Options: options}
+}
+
+func (c *InstructionCache) Clear() {
+	c.pages = [MemorySize / instructionCachePageSize]*instructionCachePage{}
+}
+
+// cacheable tells whether the bytes are plain memory that only changes through writes
+func cacheable(context *Context, address int, size int) bool {
+	if address+size > MemorySize {
+		return false
+	}
+	if context.MemoryMap == nil {
+		return true
+	}
+	for i := 0; i < size; i++ {
+		if !context.MemoryMap.isMemory(address + i) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *InstructionCache) store(address int, instruction Instruction) {
+	page := c.pages[address/instructionCachePageSize]
+	if page == nil {
+		page = &instructionCachePage{}
+		c.pages[address/instructionCachePageSize] = page
+	}
+	page[address%instructionCachePageSize] = cachedInstruction{instruction: instruction, valid: true}
+}
+
+func (c *InstructionCache) lookup(address int) (Instruction, bool) {
+	page := c.pages[address/instructionCachePageSize]
+	if page == nil || !page[address%instructionCachePageSize].valid {
+		return Instruction{}, false
+	}
+	return page[address%instructionCachePageSize].instruction, true
+}
+
+func endsBasicBlock(t InstructionType) bool {
+	switch t {
+	case IT_JumpDirectWithinSegment, IT_JumpDirectWithinSegmentShort, IT_JumpIndirectWithinSegment,
+		IT_JumpDirectIntersegment, IT_JumpIndirectIntersegment,
+		IT_InterruptTypeSpecified, IT_InterruptType3, IT_InterruptOnOverflow, IT_InterruptReturn,
+		IT_Halt:
+		return true
+	}
+	return t.IsConditionalJump() || isCall(t) || isReturn(t)
+}
+
+// decodeBlock caches the instructions after a miss up to the end of its basic block
+func (c *InstructionCache) decodeBlock(context *Context, address int, instruction Instruction) {
+	for !endsBasicBlock(instruction.Type) {
+		address += instruction.SizeInBytes
+		if _, found := c.lookup(address); found || !cacheable(context, address, maxInstructionSize) {
+			return
+		}
+		var err error
+		instruction, err = decodeAt(context, address)
+		if err != nil {
+			return
+		}
+		c.store(address, instruction)
+	}
+}
+
+func (c *InstructionCache) fetch(context *Context, address int) (Instruction, error) {
+	instruction, found := c.lookup(address)
+	if found {
+		c.Hits++
+		return instruction, nil
+	}
+
+	c.Misses++
+	instruction, err := decodeAt(context, address)
+	if err != nil || !cacheable(context, address, instruction.SizeInBytes) {
+		return instruction, err
+	}
+	c.store(address, instruction)
+	if c.Options.BasicBlocks {
+		c.decodeBlock(context, address, instruction)
+	}
+	return instruction, nil
+}
+
+// invalidate drops the cached instructions that contain the byte at address
+func (c *InstructionCache) invalidate(address int) {
+	first := address - maxInstructionSize + 1
+	if first < 0 {
+		first = 0
+	}
+	if c.pages[first/instructionCachePageSize] == nil && c.pages[address/instructionCachePageSize] == nil {
+		return
+	}
+	for start := first; start <= address; start++ {
+		page := c.pages[start/instructionCachePageSize]
+		if page == nil {
+			continue
+		}
+		entry := &page[start%instructionCachePageSize]
+		if entry.valid && start+entry.instruction.SizeInBytes > address {
+			entry.valid = false
+			c.Invalidations++
+		}
+	}
+}
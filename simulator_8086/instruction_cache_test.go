@@ -0,0 +1,151 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadSelfModifyingProgram(context *Context) {
+	program := []byte{
+		0xb9, 0x03, 0x00, // mov cx, 3
+		0x83, 0xc3, 0x01, // add bx, 1
+		0xc6, 0x06, 0x05, 0x00, 0x02, // mov byte [5], 2
+		0x83, 0xe9, 0x01, // sub cx, 1
+		0x75, 0xf3, // jne -13
+		0xf4, // hlt
+	}
+	copy(context.Memory[:], program)
+}
+
+func TestInstructionCacheSelfModifyingCode(t *testing.T) {
+	tests := []struct {
+		options InstructionCacheOptions
+		hits    uint64
+		misses  uint64
+	}{
+		{InstructionCacheOptions{}, 6, 8},
+		// the block after the first miss is decoded ahead, hlt follows the jne that ends it
+		{InstructionCacheOptions{BasicBlocks: true}, 10, 4},
+	}
+	for _, test := range tests {
+		context := &Context{InstructionCache: NewInstructionCache(test.options)}
+		loadSelfModifyingProgram(context)
+		runUntilHalted(t, context)
+
+		// the first iteration adds 1, the others the patched 2
+		require.Equal(t, int16(5), context.GetRegister(BX))
+		require.Equal(t, uint64(3), context.InstructionCache.Invalidations)
+		require.Equal(t, test.hits, context.InstructionCache.Hits)
+		require.Equal(t, test.misses, context.InstructionCache.Misses)
+	}
+}
+
+func TestInstructionCacheBasicBlocks(t *testing.T) {
+	context := &Context{InstructionCache: NewInstructionCache(InstructionCacheOptions{BasicBlocks: true})}
+	copy(context.Memory[:], []byte{
+		0xb8, 0x01, 0x00, // mov ax, 1
+		0x01, 0xc3, // add bx, ax
+		0x89, 0xd9, // mov cx, bx
+		0xf4, // hlt
+	})
+	runUntilHalted(t, context)
+	require.Equal(t, uint64(1), context.InstructionCache.Misses)
+	require.Equal(t, uint64(3), context.InstructionCache.Hits)
+}
+
+func TestInstructionCacheDeviceMemory(t *testing.T) {
+	context := &Context{InstructionCache: NewInstructionCache(InstructionCacheOptions{BasicBlocks: true})}
+	context.MemoryMap = NewMemoryMap()
+	device := &testMemoryDevice{writes: make(map[int]byte)}
+	require.NoError(t, context.MemoryMap.MapDevice(0x10, 0x10, device))
+
+	// the device reads as 00 01 02 ..., add [bx + di], al
+	for i := 0; i < 2; i++ {
+		context.InstructionPointer = 0x10
+		instruction, err := Step(context)
+		require.NoError(t, err)
+		require.Equal(t, IT_AddRegMemWithRegToEither, instruction.Type)
+	}
+	require.Equal(t, uint64(0), context.InstructionCache.Hits)
+	require.Equal(t, uint64(2), context.InstructionCache.Misses)
+}
+
+func TestInstructionCacheLockstep(t *testing.T) {
+	first, second := &Context{}, &Context{InstructionCache: NewInstructionCache(InstructionCacheOptions{BasicBlocks: true})}
+	loadSelfModifyingProgram(first)
+	loadSelfModifyingProgram(second)
+
+	result := RunLockstep(gocontext.Background(), first, second, LockstepOptions{CompareClocks: true})
+	require.Equal(t, SR_Halted, result.Reason)
+	require.Nil(t, result.Divergence)
+}
+
+func TestInstructionCacheHistory(t *testing.T) {
+	context := &Context{InstructionCache: NewInstructionCache(InstructionCacheOptions{})}
+	loadSelfModifyingProgram(context)
+	history := EnableHistory(context, HistoryConfig{})
+	for i := 0; i < 3; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+
+	// stepping back restores the add bx, 1 that is still cached as add bx, 2
+	require.NoError(t, history.StepBack(context))
+	require.NoError(t, history.StepBack(context))
+	_, err := Step(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(1), context.GetRegister(BX))
+}
+
+// BenchmarkStep runs a tight loop without decoding it again on every step
+func BenchmarkStep(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		cache *InstructionCache
+	}{
+		{"uncached", nil},
+		{"cached", NewInstructionCache(InstructionCacheOptions{})},
+		{"basic blocks", NewInstructionCache(InstructionCacheOptions{BasicBlocks: true})},
+	}
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			context := &Context{InstructionCache: benchmark.cache}
+			copy(context.Memory[:], []byte{
+				0x83, 0xc0, 0x01, // add ax, 1
+				0x01, 0xc3, // add bx, ax
+				0xeb, 0xf9, // jmp -7
+			})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := Step(context)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "instructions/s")
+		})
+	}
+}
+
+// the sets of instruction types must not depend on the order of instructions.txt
+func TestInstructionTypeSets(t *testing.T) {
+	for instructionType := IT_Invalid + 1; int(instructionType) < len(instructionNames); instructionType++ {
+		name := instructionType.Name()
+		require.Equal(t, name == "call", isCall(instructionType), name)
+		require.Equal(t, name == "ret", isReturn(instructionType), name)
+		require.Equal(t, name == "push", isPush(instructionType), name)
+		require.Equal(t, name == "pop", isPop(instructionType), name)
+		require.Equal(t, name == "xchg", isExchange(instructionType), name)
+		require.Equal(t, name == "mov" || name == "push" || name == "pop" || name == "xchg", isCopy(instructionType), name)
+
+		switch name {
+		case "call", "ret", "jmp", "int", "int3", "into", "iret", "hlt":
+			require.True(t, endsBasicBlock(instructionType), name)
+		default:
+			require.Equal(t, instructionType.IsConditionalJump(), endsBasicBlock(instructionType), name)
+		}
+	}
+}
@@ -0,0 +1,678 @@
+// Code generated by generate_instructions.go from instructions.txt; DO NOT EDIT.
+
+package simulator8086
+
+type InstructionType int
+
+const (
+	IT_Invalid InstructionType = iota
+	IT_MovRegMemToFromReg
+	IT_MovImToRegMem
+	IT_MovImToReg
+	IT_MovMemToAcc
+	IT_MovAccToMem
+	IT_MovRegMemToSegReg
+	IT_MovSegRegToRegMem
+
+	IT_PushRegMem
+	IT_PushReg
+	IT_PushSegReg
+
+	IT_PopRegMem
+	IT_PopReg
+	IT_PopSegReg
+
+	IT_ExchangeRegMemWithReg
+	IT_ExchangeRegWithAcc
+
+	IT_InFixed
+	IT_InVariable
+	IT_OutFixed
+	IT_OutVariable
+
+	IT_XLAT
+	IT_LoadEA
+	IT_LoadDS
+	IT_LoadES
+	IT_LoadAHWithFlags
+	IT_StoreAHWithFlags
+	IT_PushFlags
+	IT_PopFlags
+
+	IT_AddRegMemWithRegToEither
+	IT_AddImToRegMem
+	IT_AddImToAcc
+
+	IT_AddWithCarryRegMemWithRegToEither
+	IT_AddWithCarryImToRegMem
+	IT_AddWithCarryImToAcc
+
+	IT_IncRegMem
+	IT_IncReg
+	IT_AsciiAdjustForAdd
+	IT_DecimalAdjustForAdd
+
+	IT_SubRegMemWithRegToEither
+	IT_SubImToRegMem
+	IT_SubImFromAcc
+
+	IT_SubWithBorrowRegMemWithRegToEither
+	IT_SubWithBorrowImToRegMem
+	IT_SubWithBorrowImFromAcc
+
+	IT_DecRegMem
+	IT_DecReg
+
+	IT_Neg
+
+	IT_CmpRegMemAndReg
+	IT_CmpImWithRegMem
+	IT_CmpImWithAcc
+
+	IT_AsciiAdjustForSubtract
+	IT_DecimalAdjustForSubtract
+
+	IT_Multiply
+	IT_MultiplySigned
+	IT_AsciiAdjustForMultiply
+
+	IT_Divide
+	IT_DivideSigned
+	IT_AsciiAdjustForDivide
+
+	IT_ConvertByteToWord
+	IT_ConvertWordToDoubleWord
+
+	IT_Not
+	IT_ShiftLogicLeft
+	IT_ShiftLogicRight
+	IT_ShiftArithmeticRight
+	IT_RotateLeft
+	IT_RotateRight
+	IT_RotateThroughCarryFlagLeft
+	IT_RotateThroughCarryFlagRight
+
+	IT_AndRegMemWithRegToEither
+	IT_AndImToRegMem
+	IT_AndImToAcc
+
+	IT_TestRegMemAndReg
+	IT_TestImAndRegMem
+	IT_TestImAndAcc
+
+	IT_OrRegMemWithRegToEither
+	IT_OrImToRegMem
+	IT_OrImToAcc
+
+	IT_XorRegMemWithRegToEither
+	IT_XorImToRegMem
+	IT_XorImToAcc
+
+	IT_Repeat
+	IT_MoveByte
+	IT_CompareByte
+	IT_ScanByte
+	IT_LoadByte
+	IT_StoreByte
+
+	IT_CallDirectWithinSegment
+	IT_CallIndirectWithinSegment
+	IT_CallDirectIntersegment
+	IT_CallIndirectIntersegment
+
+	IT_JumpDirectWithinSegment
+	IT_JumpDirectWithinSegmentShort
+	IT_JumpIndirectWithinSegment
+	IT_JumpDirectIntersegment
+	IT_JumpIndirectIntersegment
+
+	IT_ReturnWithinSegment
+	IT_ReturnWithinSegmentAddingImmediateToSP
+	IT_ReturnIntersegment
+	IT_ReturnIntersegmentAddingImmediateToSP
+
+	IT_JE
+	IT_JNE
+	IT_JL
+	IT_JLE
+	IT_JB
+	IT_JBE
+	IT_JP
+	IT_JO
+	IT_JS
+	IT_JNL
+	IT_JNLE
+	IT_JNB
+	IT_JNBE
+	IT_JNP
+	IT_JNO
+	IT_JNS
+	IT_LOOP
+	IT_LOOPZ
+	IT_LOOPNZ
+	IT_JCXZ
+
+	IT_InterruptTypeSpecified
+	IT_InterruptType3
+	IT_InterruptOnOverflow
+	IT_InterruptReturn
+
+	IT_ClearCarry
+	IT_ComplementCarry
+	IT_SetCarry
+	IT_ClearDirection
+	IT_SetDirection
+	IT_ClearInterrupt
+	IT_SetInterrupt
+	IT_Halt
+	IT_Wait
+	IT_Escape
+	IT_BusLockPrefix
+)
+
+var instructionNames = [...]string{
+	IT_Invalid:                            "unknown",
+	IT_MovRegMemToFromReg:                 "mov",
+	IT_MovImToRegMem:                      "mov",
+	IT_MovImToReg:                         "mov",
+	IT_MovMemToAcc:                        "mov",
+	IT_MovAccToMem:                        "mov",
+	IT_MovRegMemToSegReg:                  "mov",
+	IT_MovSegRegToRegMem:                  "mov",
+	IT_PushRegMem:                         "push",
+	IT_PushReg:                            "push",
+	IT_PushSegReg:                         "push",
+	IT_PopRegMem:                          "pop",
+	IT_PopReg:                             "pop",
+	IT_PopSegReg:                          "pop",
+	IT_ExchangeRegMemWithReg:              "xchg",
+	IT_ExchangeRegWithAcc:                 "xchg",
+	IT_InFixed:                            "in",
+	IT_InVariable:                         "in",
+	IT_OutFixed:                           "out",
+	IT_OutVariable:                        "out",
+	IT_XLAT:                               "xlat",
+	IT_LoadEA:                             "lea",
+	IT_LoadDS:                             "lds",
+	IT_LoadES:                             "les",
+	IT_LoadAHWithFlags:                    "lahf",
+	IT_StoreAHWithFlags:                   "sahf",
+	IT_PushFlags:                          "pushf",
+	IT_PopFlags:                           "popf",
+	IT_AddRegMemWithRegToEither:           "add",
+	IT_AddImToRegMem:                      "add",
+	IT_AddImToAcc:                         "add",
+	IT_AddWithCarryRegMemWithRegToEither:  "adc",
+	IT_AddWithCarryImToRegMem:             "adc",
+	IT_AddWithCarryImToAcc:                "adc",
+	IT_IncRegMem:                          "inc",
+	IT_IncReg:                             "inc",
+	IT_AsciiAdjustForAdd:                  "aaa",
+	IT_DecimalAdjustForAdd:                "daa",
+	IT_SubRegMemWithRegToEither:           "sub",
+	IT_SubImToRegMem:                      "sub",
+	IT_SubImFromAcc:                       "sub",
+	IT_SubWithBorrowRegMemWithRegToEither: "sbb",
+	IT_SubWithBorrowImToRegMem:            "sbb",
+	IT_SubWithBorrowImFromAcc:             "sbb",
+	IT_DecRegMem:                          "dec",
+	IT_DecReg:                             "dec",
+	IT_Neg:                                "neg",
+	IT_CmpRegMemAndReg:                    "cmp",
+	IT_CmpImWithRegMem:                    "cmp",
+	IT_CmpImWithAcc:                       "cmp",
+	IT_AsciiAdjustForSubtract:             "aas",
+	IT_DecimalAdjustForSubtract:           "das",
+	IT_Multiply:                           "mul",
+	IT_MultiplySigned:                     "imul",
+	IT_AsciiAdjustForMultiply:             "aam",
+	IT_Divide:                             "div",
+	IT_DivideSigned:                       "idiv",
+	IT_AsciiAdjustForDivide:               "aad",
+	IT_ConvertByteToWord:                  "cbw",
+	IT_ConvertWordToDoubleWord:            "cwd",
+	IT_Not:                                "not",
+	IT_ShiftLogicLeft:                     "shl",
+	IT_ShiftLogicRight:                    "shr",
+	IT_ShiftArithmeticRight:               "sar",
+	IT_RotateLeft:                         "rol",
+	IT_RotateRight:                        "ror",
+	IT_RotateThroughCarryFlagLeft:         "rcl",
+	IT_RotateThroughCarryFlagRight:        "rcr",
+	IT_AndRegMemWithRegToEither:           "and",
+	IT_AndImToRegMem:                      "and",
+	IT_AndImToAcc:                         "and",
+	IT_TestRegMemAndReg:                   "test",
+	IT_TestImAndRegMem:                    "test",
+	IT_TestImAndAcc:                       "test",
+	IT_OrRegMemWithRegToEither:            "or",
+	IT_OrImToRegMem:                       "or",
+	IT_OrImToAcc:                          "or",
+	IT_XorRegMemWithRegToEither:           "xor",
+	IT_XorImToRegMem:                      "xor",
+	IT_XorImToAcc:                         "xor",
+	IT_Repeat:                             "rep",
+	IT_MoveByte:                           "movs",
+	IT_CompareByte:                        "cmps",
+	IT_ScanByte:                           "scas",
+	IT_LoadByte:                           "lods",
+	IT_StoreByte:                          "stos",
+	IT_CallDirectWithinSegment:            "call",
+	IT_CallIndirectWithinSegment:          "call",
+	IT_CallDirectIntersegment:             "call",
+	IT_CallIndirectIntersegment:           "call",
+	IT_JumpDirectWithinSegment:            "jmp",
+	IT_JumpDirectWithinSegmentShort:       "jmp",
+	IT_JumpIndirectWithinSegment:          "jmp",
+	IT_JumpDirectIntersegment:             "jmp",
+	IT_JumpIndirectIntersegment:           "jmp",
+	IT_ReturnWithinSegment:                "ret",
+	IT_ReturnWithinSegmentAddingImmediateToSP: "ret",
+	IT_ReturnIntersegment:                     "ret",
+	IT_ReturnIntersegmentAddingImmediateToSP:  "ret",
+	IT_JE:                                     "je",
+	IT_JNE:                                    "jne",
+	IT_JL:                                     "jl",
+	IT_JLE:                                    "jle",
+	IT_JB:                                     "jb",
+	IT_JBE:                                    "jbe",
+	IT_JP:                                     "jp",
+	IT_JO:                                     "jo",
+	IT_JS:                                     "js",
+	IT_JNL:                                    "jnl",
+	IT_JNLE:                                   "jnle",
+	IT_JNB:                                    "jnb",
+	IT_JNBE:                                   "jnbe",
+	IT_JNP:                                    "jnp",
+	IT_JNO:                                    "jno",
+	IT_JNS:                                    "jns",
+	IT_LOOP:                                   "loop",
+	IT_LOOPZ:                                  "loopz",
+	IT_LOOPNZ:                                 "loopnz",
+	IT_JCXZ:                                   "jcxz",
+	IT_InterruptTypeSpecified:                 "int",
+	IT_InterruptType3:                         "int3",
+	IT_InterruptOnOverflow:                    "into",
+	IT_InterruptReturn:                        "iret",
+	IT_ClearCarry:                             "clc",
+	IT_ComplementCarry:                        "cmc",
+	IT_SetCarry:                               "stc",
+	IT_ClearDirection:                         "cld",
+	IT_SetDirection:                           "std",
+	IT_ClearInterrupt:                         "cli",
+	IT_SetInterrupt:                           "sti",
+	IT_Halt:                                   "hlt",
+	IT_Wait:                                   "wait",
+	IT_Escape:                                 "esc",
+	IT_BusLockPrefix:                          "lock",
+}
+
+var instructionEncodings = [...]instructionEncoding{
+	IT_Invalid:                            {},
+	IT_MovRegMemToFromReg:                 {opcode: 0x88, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_MovImToRegMem:                      {opcode: 0xc6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b000, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_MovImToReg:                         {opcode: 0xb0, opcodeMask: 0xf0, w: 0x08, register: 0x07, operands: [2]operandKind{operandRegister, operandData}},
+	IT_MovMemToAcc:                        {opcode: 0xa0, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandAddress}},
+	IT_MovAccToMem:                        {opcode: 0xa2, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAddress, operandAccumulator}},
+	IT_MovRegMemToSegReg:                  {opcode: 0x8e, opcodeMask: 0xff, modrm: modrmSegment, modrmReg: 0b000, modrmRegMask: 0b100, operands: [2]operandKind{operandSegment, operandRegisterMemory}},
+	IT_MovSegRegToRegMem:                  {opcode: 0x8c, opcodeMask: 0xff, modrm: modrmSegment, modrmReg: 0b000, modrmRegMask: 0b100, operands: [2]operandKind{operandRegisterMemory, operandSegment}},
+	IT_PushRegMem:                         {opcode: 0xff, opcodeMask: 0xff, modrm: modrmFixed, modrmReg: 0b110, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_PushReg:                            {opcode: 0x50, opcodeMask: 0xf8, register: 0x07, operands: [2]operandKind{operandRegister}},
+	IT_PushSegReg:                         {opcode: 0x06, opcodeMask: 0xe7, segment: 0x18, operands: [2]operandKind{operandSegment}},
+	IT_PopRegMem:                          {opcode: 0x8f, opcodeMask: 0xff, modrm: modrmFixed, modrmReg: 0b000, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_PopReg:                             {opcode: 0x58, opcodeMask: 0xf8, register: 0x07, operands: [2]operandKind{operandRegister}},
+	IT_PopSegReg:                          {opcode: 0x07, opcodeMask: 0xe7, segment: 0x18, operands: [2]operandKind{operandSegment}},
+	IT_ExchangeRegMemWithReg:              {opcode: 0x86, opcodeMask: 0xfe, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegister, operandRegisterMemory}},
+	IT_ExchangeRegWithAcc:                 {opcode: 0x90, opcodeMask: 0xf8, register: 0x07, operands: [2]operandKind{operandAccumulator, operandRegister}},
+	IT_InFixed:                            {opcode: 0xe4, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandPort}},
+	IT_InVariable:                         {opcode: 0xec, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandDX}},
+	IT_OutFixed:                           {opcode: 0xe6, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandPort, operandAccumulator}},
+	IT_OutVariable:                        {opcode: 0xee, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandDX, operandAccumulator}},
+	IT_XLAT:                               {opcode: 0xd7, opcodeMask: 0xff},
+	IT_LoadEA:                             {opcode: 0x8d, opcodeMask: 0xff, modrm: modrmRegister, operands: [2]operandKind{operandRegister, operandRegisterMemory}},
+	IT_LoadDS:                             {opcode: 0xc5, opcodeMask: 0xff, modrm: modrmRegister, operands: [2]operandKind{operandRegister, operandFarPointer}},
+	IT_LoadES:                             {opcode: 0xc4, opcodeMask: 0xff, modrm: modrmRegister, operands: [2]operandKind{operandRegister, operandFarPointer}},
+	IT_LoadAHWithFlags:                    {opcode: 0x9f, opcodeMask: 0xff},
+	IT_StoreAHWithFlags:                   {opcode: 0x9e, opcodeMask: 0xff},
+	IT_PushFlags:                          {opcode: 0x9c, opcodeMask: 0xff},
+	IT_PopFlags:                           {opcode: 0x9d, opcodeMask: 0xff},
+	IT_AddRegMemWithRegToEither:           {opcode: 0x00, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_AddImToRegMem:                      {opcode: 0x80, opcodeMask: 0xfc, w: 0x01, s: 0x02, modrm: modrmFixed, modrmReg: 0b000, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_AddImToAcc:                         {opcode: 0x04, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_AddWithCarryRegMemWithRegToEither:  {opcode: 0x10, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_AddWithCarryImToRegMem:             {opcode: 0x80, opcodeMask: 0xfc, w: 0x01, s: 0x02, modrm: modrmFixed, modrmReg: 0b010, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_AddWithCarryImToAcc:                {opcode: 0x14, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_IncRegMem:                          {opcode: 0xfe, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b000, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_IncReg:                             {opcode: 0x40, opcodeMask: 0xf8, register: 0x07, operands: [2]operandKind{operandRegister}},
+	IT_AsciiAdjustForAdd:                  {opcode: 0x37, opcodeMask: 0xff},
+	IT_DecimalAdjustForAdd:                {opcode: 0x27, opcodeMask: 0xff},
+	IT_SubRegMemWithRegToEither:           {opcode: 0x28, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_SubImToRegMem:                      {opcode: 0x80, opcodeMask: 0xfc, w: 0x01, s: 0x02, modrm: modrmFixed, modrmReg: 0b101, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_SubImFromAcc:                       {opcode: 0x2c, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_SubWithBorrowRegMemWithRegToEither: {opcode: 0x18, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_SubWithBorrowImToRegMem:            {opcode: 0x80, opcodeMask: 0xfc, w: 0x01, s: 0x02, modrm: modrmFixed, modrmReg: 0b011, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_SubWithBorrowImFromAcc:             {opcode: 0x1c, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_DecRegMem:                          {opcode: 0xfe, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b001, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_DecReg:                             {opcode: 0x48, opcodeMask: 0xf8, register: 0x07, operands: [2]operandKind{operandRegister}},
+	IT_Neg:                                {opcode: 0xf6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b011, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_CmpRegMemAndReg:                    {opcode: 0x38, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_CmpImWithRegMem:                    {opcode: 0x80, opcodeMask: 0xfc, w: 0x01, s: 0x02, modrm: modrmFixed, modrmReg: 0b111, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_CmpImWithAcc:                       {opcode: 0x3c, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_AsciiAdjustForSubtract:             {opcode: 0x3f, opcodeMask: 0xff},
+	IT_DecimalAdjustForSubtract:           {opcode: 0x2f, opcodeMask: 0xff},
+	IT_Multiply:                           {opcode: 0xf6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b100, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_MultiplySigned:                     {opcode: 0xf6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b101, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_AsciiAdjustForMultiply:             {opcode: 0xd4, opcodeMask: 0xff, secondByte: 0x0a, hasSecondByte: true},
+	IT_Divide:                             {opcode: 0xf6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b110, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_DivideSigned:                       {opcode: 0xf6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b111, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_AsciiAdjustForDivide:               {opcode: 0xd5, opcodeMask: 0xff, secondByte: 0x0a, hasSecondByte: true},
+	IT_ConvertByteToWord:                  {opcode: 0x98, opcodeMask: 0xff},
+	IT_ConvertWordToDoubleWord:            {opcode: 0x99, opcodeMask: 0xff},
+	IT_Not:                                {opcode: 0xf6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b010, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_ShiftLogicLeft:                     {opcode: 0xd0, opcodeMask: 0xfc, w: 0x01, v: 0x02, modrm: modrmFixed, modrmReg: 0b100, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandCount}},
+	IT_ShiftLogicRight:                    {opcode: 0xd0, opcodeMask: 0xfc, w: 0x01, v: 0x02, modrm: modrmFixed, modrmReg: 0b101, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandCount}},
+	IT_ShiftArithmeticRight:               {opcode: 0xd0, opcodeMask: 0xfc, w: 0x01, v: 0x02, modrm: modrmFixed, modrmReg: 0b111, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandCount}},
+	IT_RotateLeft:                         {opcode: 0xd0, opcodeMask: 0xfc, w: 0x01, v: 0x02, modrm: modrmFixed, modrmReg: 0b000, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandCount}},
+	IT_RotateRight:                        {opcode: 0xd0, opcodeMask: 0xfc, w: 0x01, v: 0x02, modrm: modrmFixed, modrmReg: 0b001, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandCount}},
+	IT_RotateThroughCarryFlagLeft:         {opcode: 0xd0, opcodeMask: 0xfc, w: 0x01, v: 0x02, modrm: modrmFixed, modrmReg: 0b010, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandCount}},
+	IT_RotateThroughCarryFlagRight:        {opcode: 0xd0, opcodeMask: 0xfc, w: 0x01, v: 0x02, modrm: modrmFixed, modrmReg: 0b011, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandCount}},
+	IT_AndRegMemWithRegToEither:           {opcode: 0x20, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_AndImToRegMem:                      {opcode: 0x80, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b100, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_AndImToAcc:                         {opcode: 0x24, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_TestRegMemAndReg:                   {opcode: 0x84, opcodeMask: 0xfe, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_TestImAndRegMem:                    {opcode: 0xf6, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b000, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_TestImAndAcc:                       {opcode: 0xa8, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_OrRegMemWithRegToEither:            {opcode: 0x08, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_OrImToRegMem:                       {opcode: 0x80, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b001, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_OrImToAcc:                          {opcode: 0x0c, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_XorRegMemWithRegToEither:           {opcode: 0x30, opcodeMask: 0xfc, d: 0x02, w: 0x01, modrm: modrmRegister, operands: [2]operandKind{operandRegisterMemory, operandRegister}},
+	IT_XorImToRegMem:                      {opcode: 0x80, opcodeMask: 0xfe, w: 0x01, modrm: modrmFixed, modrmReg: 0b110, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory, operandData}},
+	IT_XorImToAcc:                         {opcode: 0x34, opcodeMask: 0xfe, w: 0x01, operands: [2]operandKind{operandAccumulator, operandData}},
+	IT_Repeat:                             {opcode: 0xf2, opcodeMask: 0xfe, z: 0x01},
+	IT_MoveByte:                           {opcode: 0xa4, opcodeMask: 0xfe, w: 0x01},
+	IT_CompareByte:                        {opcode: 0xa6, opcodeMask: 0xfe, w: 0x01},
+	IT_ScanByte:                           {opcode: 0xae, opcodeMask: 0xfe, w: 0x01},
+	IT_LoadByte:                           {opcode: 0xac, opcodeMask: 0xfe, w: 0x01},
+	IT_StoreByte:                          {opcode: 0xaa, opcodeMask: 0xfe, w: 0x01},
+	IT_CallDirectWithinSegment:            {opcode: 0xe8, opcodeMask: 0xff, operands: [2]operandKind{operandLabel16}},
+	IT_CallIndirectWithinSegment:          {opcode: 0xff, opcodeMask: 0xff, modrm: modrmFixed, modrmReg: 0b010, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_CallDirectIntersegment:             {opcode: 0x9a, opcodeMask: 0xff, operands: [2]operandKind{operandFar}},
+	IT_CallIndirectIntersegment:           {opcode: 0xff, opcodeMask: 0xff, modrm: modrmFixed, modrmReg: 0b011, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_JumpDirectWithinSegment:            {opcode: 0xe9, opcodeMask: 0xff, operands: [2]operandKind{operandLabel16}},
+	IT_JumpDirectWithinSegmentShort:       {opcode: 0xeb, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JumpIndirectWithinSegment:          {opcode: 0xff, opcodeMask: 0xff, modrm: modrmFixed, modrmReg: 0b100, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_JumpDirectIntersegment:             {opcode: 0xea, opcodeMask: 0xff, operands: [2]operandKind{operandFar}},
+	IT_JumpIndirectIntersegment:           {opcode: 0xff, opcodeMask: 0xff, modrm: modrmFixed, modrmReg: 0b101, modrmRegMask: 0b111, operands: [2]operandKind{operandRegisterMemory}},
+	IT_ReturnWithinSegment:                {opcode: 0xc3, opcodeMask: 0xff},
+	IT_ReturnWithinSegmentAddingImmediateToSP: {opcode: 0xc2, opcodeMask: 0xff, operands: [2]operandKind{operandData16}},
+	IT_ReturnIntersegment:                     {opcode: 0xcb, opcodeMask: 0xff},
+	IT_ReturnIntersegmentAddingImmediateToSP:  {opcode: 0xca, opcodeMask: 0xff, operands: [2]operandKind{operandData16}},
+	IT_JE:                                     {opcode: 0x74, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNE:                                    {opcode: 0x75, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JL:                                     {opcode: 0x7c, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JLE:                                    {opcode: 0x7e, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JB:                                     {opcode: 0x72, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JBE:                                    {opcode: 0x76, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JP:                                     {opcode: 0x7a, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JO:                                     {opcode: 0x70, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JS:                                     {opcode: 0x78, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNL:                                    {opcode: 0x7d, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNLE:                                   {opcode: 0x7f, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNB:                                    {opcode: 0x73, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNBE:                                   {opcode: 0x77, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNP:                                    {opcode: 0x7b, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNO:                                    {opcode: 0x71, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JNS:                                    {opcode: 0x79, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_LOOP:                                   {opcode: 0xe2, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_LOOPZ:                                  {opcode: 0xe1, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_LOOPNZ:                                 {opcode: 0xe0, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_JCXZ:                                   {opcode: 0xe3, opcodeMask: 0xff, operands: [2]operandKind{operandLabel8}},
+	IT_InterruptTypeSpecified:                 {opcode: 0xcd, opcodeMask: 0xff, operands: [2]operandKind{operandData8}},
+	IT_InterruptType3:                         {opcode: 0xcc, opcodeMask: 0xff},
+	IT_InterruptOnOverflow:                    {opcode: 0xce, opcodeMask: 0xff},
+	IT_InterruptReturn:                        {opcode: 0xcf, opcodeMask: 0xff},
+	IT_ClearCarry:                             {opcode: 0xf8, opcodeMask: 0xff},
+	IT_ComplementCarry:                        {opcode: 0xf5, opcodeMask: 0xff},
+	IT_SetCarry:                               {opcode: 0xf9, opcodeMask: 0xff},
+	IT_ClearDirection:                         {opcode: 0xfc, opcodeMask: 0xff},
+	IT_SetDirection:                           {opcode: 0xfd, opcodeMask: 0xff},
+	IT_ClearInterrupt:                         {opcode: 0xfa, opcodeMask: 0xff},
+	IT_SetInterrupt:                           {opcode: 0xfb, opcodeMask: 0xff},
+	IT_Halt:                                   {opcode: 0xf4, opcodeMask: 0xff},
+	IT_Wait:                                   {opcode: 0x9b, opcodeMask: 0xff},
+	IT_Escape:                                 {opcode: 0xd8, opcodeMask: 0xf8, escape: 0x07, modrm: modrmEscape, operands: [2]operandKind{operandEscape, operandFarPointer}},
+	IT_BusLockPrefix:                          {opcode: 0xf0, opcodeMask: 0xff},
+}
+
+var opcodeTable = [256]opcodeEntry{
+	0x00: {instructionType: IT_AddRegMemWithRegToEither},
+	0x01: {instructionType: IT_AddRegMemWithRegToEither},
+	0x02: {instructionType: IT_AddRegMemWithRegToEither},
+	0x03: {instructionType: IT_AddRegMemWithRegToEither},
+	0x04: {instructionType: IT_AddImToAcc},
+	0x05: {instructionType: IT_AddImToAcc},
+	0x06: {instructionType: IT_PushSegReg},
+	0x07: {instructionType: IT_PopSegReg},
+	0x08: {instructionType: IT_OrRegMemWithRegToEither},
+	0x09: {instructionType: IT_OrRegMemWithRegToEither},
+	0x0a: {instructionType: IT_OrRegMemWithRegToEither},
+	0x0b: {instructionType: IT_OrRegMemWithRegToEither},
+	0x0c: {instructionType: IT_OrImToAcc},
+	0x0d: {instructionType: IT_OrImToAcc},
+	0x0e: {instructionType: IT_PushSegReg},
+	0x0f: {instructionType: IT_PopSegReg},
+	0x10: {instructionType: IT_AddWithCarryRegMemWithRegToEither},
+	0x11: {instructionType: IT_AddWithCarryRegMemWithRegToEither},
+	0x12: {instructionType: IT_AddWithCarryRegMemWithRegToEither},
+	0x13: {instructionType: IT_AddWithCarryRegMemWithRegToEither},
+	0x14: {instructionType: IT_AddWithCarryImToAcc},
+	0x15: {instructionType: IT_AddWithCarryImToAcc},
+	0x16: {instructionType: IT_PushSegReg},
+	0x17: {instructionType: IT_PopSegReg},
+	0x18: {instructionType: IT_SubWithBorrowRegMemWithRegToEither},
+	0x19: {instructionType: IT_SubWithBorrowRegMemWithRegToEither},
+	0x1a: {instructionType: IT_SubWithBorrowRegMemWithRegToEither},
+	0x1b: {instructionType: IT_SubWithBorrowRegMemWithRegToEither},
+	0x1c: {instructionType: IT_SubWithBorrowImFromAcc},
+	0x1d: {instructionType: IT_SubWithBorrowImFromAcc},
+	0x1e: {instructionType: IT_PushSegReg},
+	0x1f: {instructionType: IT_PopSegReg},
+	0x20: {instructionType: IT_AndRegMemWithRegToEither},
+	0x21: {instructionType: IT_AndRegMemWithRegToEither},
+	0x22: {instructionType: IT_AndRegMemWithRegToEither},
+	0x23: {instructionType: IT_AndRegMemWithRegToEither},
+	0x24: {instructionType: IT_AndImToAcc},
+	0x25: {instructionType: IT_AndImToAcc},
+	0x27: {instructionType: IT_DecimalAdjustForAdd},
+	0x28: {instructionType: IT_SubRegMemWithRegToEither},
+	0x29: {instructionType: IT_SubRegMemWithRegToEither},
+	0x2a: {instructionType: IT_SubRegMemWithRegToEither},
+	0x2b: {instructionType: IT_SubRegMemWithRegToEither},
+	0x2c: {instructionType: IT_SubImFromAcc},
+	0x2d: {instructionType: IT_SubImFromAcc},
+	0x2f: {instructionType: IT_DecimalAdjustForSubtract},
+	0x30: {instructionType: IT_XorRegMemWithRegToEither},
+	0x31: {instructionType: IT_XorRegMemWithRegToEither},
+	0x32: {instructionType: IT_XorRegMemWithRegToEither},
+	0x33: {instructionType: IT_XorRegMemWithRegToEither},
+	0x34: {instructionType: IT_XorImToAcc},
+	0x35: {instructionType: IT_XorImToAcc},
+	0x37: {instructionType: IT_AsciiAdjustForAdd},
+	0x38: {instructionType: IT_CmpRegMemAndReg},
+	0x39: {instructionType: IT_CmpRegMemAndReg},
+	0x3a: {instructionType: IT_CmpRegMemAndReg},
+	0x3b: {instructionType: IT_CmpRegMemAndReg},
+	0x3c: {instructionType: IT_CmpImWithAcc},
+	0x3d: {instructionType: IT_CmpImWithAcc},
+	0x3f: {instructionType: IT_AsciiAdjustForSubtract},
+	0x40: {instructionType: IT_IncReg},
+	0x41: {instructionType: IT_IncReg},
+	0x42: {instructionType: IT_IncReg},
+	0x43: {instructionType: IT_IncReg},
+	0x44: {instructionType: IT_IncReg},
+	0x45: {instructionType: IT_IncReg},
+	0x46: {instructionType: IT_IncReg},
+	0x47: {instructionType: IT_IncReg},
+	0x48: {instructionType: IT_DecReg},
+	0x49: {instructionType: IT_DecReg},
+	0x4a: {instructionType: IT_DecReg},
+	0x4b: {instructionType: IT_DecReg},
+	0x4c: {instructionType: IT_DecReg},
+	0x4d: {instructionType: IT_DecReg},
+	0x4e: {instructionType: IT_DecReg},
+	0x4f: {instructionType: IT_DecReg},
+	0x50: {instructionType: IT_PushReg},
+	0x51: {instructionType: IT_PushReg},
+	0x52: {instructionType: IT_PushReg},
+	0x53: {instructionType: IT_PushReg},
+	0x54: {instructionType: IT_PushReg},
+	0x55: {instructionType: IT_PushReg},
+	0x56: {instructionType: IT_PushReg},
+	0x57: {instructionType: IT_PushReg},
+	0x58: {instructionType: IT_PopReg},
+	0x59: {instructionType: IT_PopReg},
+	0x5a: {instructionType: IT_PopReg},
+	0x5b: {instructionType: IT_PopReg},
+	0x5c: {instructionType: IT_PopReg},
+	0x5d: {instructionType: IT_PopReg},
+	0x5e: {instructionType: IT_PopReg},
+	0x5f: {instructionType: IT_PopReg},
+	0x70: {instructionType: IT_JO},
+	0x71: {instructionType: IT_JNO},
+	0x72: {instructionType: IT_JB},
+	0x73: {instructionType: IT_JNB},
+	0x74: {instructionType: IT_JE},
+	0x75: {instructionType: IT_JNE},
+	0x76: {instructionType: IT_JBE},
+	0x77: {instructionType: IT_JNBE},
+	0x78: {instructionType: IT_JS},
+	0x79: {instructionType: IT_JNS},
+	0x7a: {instructionType: IT_JP},
+	0x7b: {instructionType: IT_JNP},
+	0x7c: {instructionType: IT_JL},
+	0x7d: {instructionType: IT_JNL},
+	0x7e: {instructionType: IT_JLE},
+	0x7f: {instructionType: IT_JNLE},
+	0x80: {group: &[8]InstructionType{0: IT_AddImToRegMem, 1: IT_OrImToRegMem, 2: IT_AddWithCarryImToRegMem, 3: IT_SubWithBorrowImToRegMem, 4: IT_AndImToRegMem, 5: IT_SubImToRegMem, 6: IT_XorImToRegMem, 7: IT_CmpImWithRegMem}},
+	0x81: {group: &[8]InstructionType{0: IT_AddImToRegMem, 1: IT_OrImToRegMem, 2: IT_AddWithCarryImToRegMem, 3: IT_SubWithBorrowImToRegMem, 4: IT_AndImToRegMem, 5: IT_SubImToRegMem, 6: IT_XorImToRegMem, 7: IT_CmpImWithRegMem}},
+	0x82: {group: &[8]InstructionType{0: IT_AddImToRegMem, 2: IT_AddWithCarryImToRegMem, 3: IT_SubWithBorrowImToRegMem, 5: IT_SubImToRegMem, 7: IT_CmpImWithRegMem}},
+	0x83: {group: &[8]InstructionType{0: IT_AddImToRegMem, 2: IT_AddWithCarryImToRegMem, 3: IT_SubWithBorrowImToRegMem, 5: IT_SubImToRegMem, 7: IT_CmpImWithRegMem}},
+	0x84: {instructionType: IT_TestRegMemAndReg},
+	0x85: {instructionType: IT_TestRegMemAndReg},
+	0x86: {instructionType: IT_ExchangeRegMemWithReg},
+	0x87: {instructionType: IT_ExchangeRegMemWithReg},
+	0x88: {instructionType: IT_MovRegMemToFromReg},
+	0x89: {instructionType: IT_MovRegMemToFromReg},
+	0x8a: {instructionType: IT_MovRegMemToFromReg},
+	0x8b: {instructionType: IT_MovRegMemToFromReg},
+	0x8c: {group: &[8]InstructionType{0: IT_MovSegRegToRegMem, 1: IT_MovSegRegToRegMem, 2: IT_MovSegRegToRegMem, 3: IT_MovSegRegToRegMem}},
+	0x8d: {instructionType: IT_LoadEA},
+	0x8e: {group: &[8]InstructionType{0: IT_MovRegMemToSegReg, 1: IT_MovRegMemToSegReg, 2: IT_MovRegMemToSegReg, 3: IT_MovRegMemToSegReg}},
+	0x8f: {group: &[8]InstructionType{0: IT_PopRegMem}},
+	0x90: {instructionType: IT_ExchangeRegWithAcc},
+	0x91: {instructionType: IT_ExchangeRegWithAcc},
+	0x92: {instructionType: IT_ExchangeRegWithAcc},
+	0x93: {instructionType: IT_ExchangeRegWithAcc},
+	0x94: {instructionType: IT_ExchangeRegWithAcc},
+	0x95: {instructionType: IT_ExchangeRegWithAcc},
+	0x96: {instructionType: IT_ExchangeRegWithAcc},
+	0x97: {instructionType: IT_ExchangeRegWithAcc},
+	0x98: {instructionType: IT_ConvertByteToWord},
+	0x99: {instructionType: IT_ConvertWordToDoubleWord},
+	0x9a: {instructionType: IT_CallDirectIntersegment},
+	0x9b: {instructionType: IT_Wait},
+	0x9c: {instructionType: IT_PushFlags},
+	0x9d: {instructionType: IT_PopFlags},
+	0x9e: {instructionType: IT_StoreAHWithFlags},
+	0x9f: {instructionType: IT_LoadAHWithFlags},
+	0xa0: {instructionType: IT_MovMemToAcc},
+	0xa1: {instructionType: IT_MovMemToAcc},
+	0xa2: {instructionType: IT_MovAccToMem},
+	0xa3: {instructionType: IT_MovAccToMem},
+	0xa4: {instructionType: IT_MoveByte},
+	0xa5: {instructionType: IT_MoveByte},
+	0xa6: {instructionType: IT_CompareByte},
+	0xa7: {instructionType: IT_CompareByte},
+	0xa8: {instructionType: IT_TestImAndAcc},
+	0xa9: {instructionType: IT_TestImAndAcc},
+	0xaa: {instructionType: IT_StoreByte},
+	0xab: {instructionType: IT_StoreByte},
+	0xac: {instructionType: IT_LoadByte},
+	0xad: {instructionType: IT_LoadByte},
+	0xae: {instructionType: IT_ScanByte},
+	0xaf: {instructionType: IT_ScanByte},
+	0xb0: {instructionType: IT_MovImToReg},
+	0xb1: {instructionType: IT_MovImToReg},
+	0xb2: {instructionType: IT_MovImToReg},
+	0xb3: {instructionType: IT_MovImToReg},
+	0xb4: {instructionType: IT_MovImToReg},
+	0xb5: {instructionType: IT_MovImToReg},
+	0xb6: {instructionType: IT_MovImToReg},
+	0xb7: {instructionType: IT_MovImToReg},
+	0xb8: {instructionType: IT_MovImToReg},
+	0xb9: {instructionType: IT_MovImToReg},
+	0xba: {instructionType: IT_MovImToReg},
+	0xbb: {instructionType: IT_MovImToReg},
+	0xbc: {instructionType: IT_MovImToReg},
+	0xbd: {instructionType: IT_MovImToReg},
+	0xbe: {instructionType: IT_MovImToReg},
+	0xbf: {instructionType: IT_MovImToReg},
+	0xc2: {instructionType: IT_ReturnWithinSegmentAddingImmediateToSP},
+	0xc3: {instructionType: IT_ReturnWithinSegment},
+	0xc4: {instructionType: IT_LoadES},
+	0xc5: {instructionType: IT_LoadDS},
+	0xc6: {group: &[8]InstructionType{0: IT_MovImToRegMem}},
+	0xc7: {group: &[8]InstructionType{0: IT_MovImToRegMem}},
+	0xca: {instructionType: IT_ReturnIntersegmentAddingImmediateToSP},
+	0xcb: {instructionType: IT_ReturnIntersegment},
+	0xcc: {instructionType: IT_InterruptType3},
+	0xcd: {instructionType: IT_InterruptTypeSpecified},
+	0xce: {instructionType: IT_InterruptOnOverflow},
+	0xcf: {instructionType: IT_InterruptReturn},
+	0xd0: {group: &[8]InstructionType{0: IT_RotateLeft, 1: IT_RotateRight, 2: IT_RotateThroughCarryFlagLeft, 3: IT_RotateThroughCarryFlagRight, 4: IT_ShiftLogicLeft, 5: IT_ShiftLogicRight, 7: IT_ShiftArithmeticRight}},
+	0xd1: {group: &[8]InstructionType{0: IT_RotateLeft, 1: IT_RotateRight, 2: IT_RotateThroughCarryFlagLeft, 3: IT_RotateThroughCarryFlagRight, 4: IT_ShiftLogicLeft, 5: IT_ShiftLogicRight, 7: IT_ShiftArithmeticRight}},
+	0xd2: {group: &[8]InstructionType{0: IT_RotateLeft, 1: IT_RotateRight, 2: IT_RotateThroughCarryFlagLeft, 3: IT_RotateThroughCarryFlagRight, 4: IT_ShiftLogicLeft, 5: IT_ShiftLogicRight, 7: IT_ShiftArithmeticRight}},
+	0xd3: {group: &[8]InstructionType{0: IT_RotateLeft, 1: IT_RotateRight, 2: IT_RotateThroughCarryFlagLeft, 3: IT_RotateThroughCarryFlagRight, 4: IT_ShiftLogicLeft, 5: IT_ShiftLogicRight, 7: IT_ShiftArithmeticRight}},
+	0xd4: {instructionType: IT_AsciiAdjustForMultiply},
+	0xd5: {instructionType: IT_AsciiAdjustForDivide},
+	0xd7: {instructionType: IT_XLAT},
+	0xd8: {instructionType: IT_Escape},
+	0xd9: {instructionType: IT_Escape},
+	0xda: {instructionType: IT_Escape},
+	0xdb: {instructionType: IT_Escape},
+	0xdc: {instructionType: IT_Escape},
+	0xdd: {instructionType: IT_Escape},
+	0xde: {instructionType: IT_Escape},
+	0xdf: {instructionType: IT_Escape},
+	0xe0: {instructionType: IT_LOOPNZ},
+	0xe1: {instructionType: IT_LOOPZ},
+	0xe2: {instructionType: IT_LOOP},
+	0xe3: {instructionType: IT_JCXZ},
+	0xe4: {instructionType: IT_InFixed},
+	0xe5: {instructionType: IT_InFixed},
+	0xe6: {instructionType: IT_OutFixed},
+	0xe7: {instructionType: IT_OutFixed},
+	0xe8: {instructionType: IT_CallDirectWithinSegment},
+	0xe9: {instructionType: IT_JumpDirectWithinSegment},
+	0xea: {instructionType: IT_JumpDirectIntersegment},
+	0xeb: {instructionType: IT_JumpDirectWithinSegmentShort},
+	0xec: {instructionType: IT_InVariable},
+	0xed: {instructionType: IT_InVariable},
+	0xee: {instructionType: IT_OutVariable},
+	0xef: {instructionType: IT_OutVariable},
+	0xf0: {instructionType: IT_BusLockPrefix},
+	0xf2: {instructionType: IT_Repeat},
+	0xf3: {instructionType: IT_Repeat},
+	0xf4: {instructionType: IT_Halt},
+	0xf5: {instructionType: IT_ComplementCarry},
+	0xf6: {group: &[8]InstructionType{0: IT_TestImAndRegMem, 2: IT_Not, 3: IT_Neg, 4: IT_Multiply, 5: IT_MultiplySigned, 6: IT_Divide, 7: IT_DivideSigned}},
+	0xf7: {group: &[8]InstructionType{0: IT_TestImAndRegMem, 2: IT_Not, 3: IT_Neg, 4: IT_Multiply, 5: IT_MultiplySigned, 6: IT_Divide, 7: IT_DivideSigned}},
+	0xf8: {instructionType: IT_ClearCarry},
+	0xf9: {instructionType: IT_SetCarry},
+	0xfa: {instructionType: IT_ClearInterrupt},
+	0xfb: {instructionType: IT_SetInterrupt},
+	0xfc: {instructionType: IT_ClearDirection},
+	0xfd: {instructionType: IT_SetDirection},
+	0xfe: {group: &[8]InstructionType{0: IT_IncRegMem, 1: IT_DecRegMem}},
+	0xff: {group: &[8]InstructionType{0: IT_IncRegMem, 1: IT_DecRegMem, 2: IT_CallIndirectWithinSegment, 3: IT_CallIndirectIntersegment, 4: IT_JumpIndirectWithinSegment, 5: IT_JumpIndirectIntersegment, 6: IT_PushRegMem}},
+}
@@ -0,0 +1,208 @@
+package simulator8086
+
+import (
+	"bufio"
+	gocontext "context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type MemoryAccess struct {
+	Address int
+	Size    int
+	Value   uint16
+}
+
+// writeRecorder collects the memory writes of one step
+type writeRecorder struct {
+	BaseObserver
+	writes []MemoryAccess
+}
+
+func (r *writeRecorder) MemoryWrite(context *Context, segment int16, address int, size int, value uint16) {
+	r.writes = append(r.writes, MemoryAccess{Address: address, Size: size, Value: value})
+}
+
+type LockstepOptions struct {
+	// maximum number of steps, 0 means no limit
+	MaxInstructions uint64
+	// number of instructions before the divergence kept for the report, defaults to 16
+	HistorySize int
+	// the clocks have to match too, to compare timing models
+	CompareClocks bool
+}
+
+// LockstepState is the state of one context after a step
+type LockstepState struct {
+	Instruction string
+	Address     int
+	Registers   [24]byte
+	Flags       uint16
+	Clocks      uint64
+	Halted      bool
+	Writes      []MemoryAccess
+	Err         error
+}
+
+func lockstepState(context *Context, instruction Instruction, writes []MemoryAccess, err error) LockstepState {
+	return LockstepState{
+		Instruction: strings.TrimSuffix(instruction.String(), "\n"),
+		Address:     context.InstructionAddress(),
+		Registers:   context.Registers,
+		Flags:       context.FlagsWord(),
+		Clocks:      context.Clocks,
+		Halted:      context.Halted,
+		Writes:      append([]MemoryAccess{}, writes...),
+		Err:         err,
+	}
+}
+
+type LockstepDivergence struct {
+	// the step that diverged, counted from 0
+	Step uint64
+	// what differs first: instruction, fault, ip, registers, flags, memory writes, halted or clocks
+	Difference string
+	States     [2]LockstepState
+	// the instructions executed before the divergent one, oldest first, as physical address and disassembly
+	History []string
+}
+
+func (d *LockstepDivergence) Error() string {
+	return fmt.Sprintf("lockstep diverged in %s at step %d", d.Difference, d.Step)
+}
+
+func writeLockstepState(writer io.Writer, name string, state LockstepState) {
+	fmt.Fprintf(writer, "%s: %s, next instruction at 0x%05x\n", name, state.Instruction, state.Address)
+	for _, register := range []RegisterName{AX, BX, CX, DX, SP, BP, SI, DI, CS, DS, ES, SS} {
+		position, _ := getPositionAndWide(register)
+		fmt.Fprintf(writer, "  %s: 0x%02x%02x", register, state.Registers[position], state.Registers[position+1])
+	}
+	fmt.Fprintf(writer, "\n  flags: 0x%04x clocks: %d halted: %t\n", state.Flags, state.Clocks, state.Halted)
+	for _, write := range state.Writes {
+		fmt.Fprintf(writer, "  write of 0x%0*x to 0x%05x\n", write.Size*2, write.Value, write.Address)
+	}
+	if state.Err != nil {
+		fmt.Fprintf(writer, "  error: %s\n", state.Err)
+	}
+}
+
+// WriteReport prints the last instructions before the divergence and the states of both contexts after it
+func (d *LockstepDivergence) WriteReport(writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	fmt.Fprintf(buffered, "%s\n", d.Error())
+	if len(d.History) != 0 {
+		fmt.Fprintf(buffered, "last %d instructions:\n", len(d.History))
+		for _, line := range d.History {
+			fmt.Fprintf(buffered, "  %s\n", line)
+		}
+	}
+	writeLockstepState(buffered, "first", d.States[0])
+	writeLockstepState(buffered, "second", d.States[1])
+	return buffered.Flush()
+}
+
+func equalWrites(a []MemoryAccess, b []MemoryAccess) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (o LockstepOptions) difference(a LockstepState, b LockstepState) string {
+	switch {
+	case a.Instruction != b.Instruction:
+		return "instruction"
+	case (a.Err == nil) != (b.Err == nil) || (a.Err != nil && a.Err.Error() != b.Err.Error()):
+		return "fault"
+	case a.Address != b.Address:
+		return "ip"
+	case a.Registers != b.Registers:
+		return "registers"
+	case a.Flags != b.Flags:
+		return "flags"
+	case !equalWrites(a.Writes, b.Writes):
+		return "memory writes"
+	case a.Halted != b.Halted:
+		return "halted"
+	case o.CompareClocks && a.Clocks != b.Clocks:
+		return "clocks"
+	}
+	return ""
+}
+
+type LockstepResult struct {
+	Reason StopReason
+	Steps  uint64
+	// the first difference, Reason is SR_Fault then
+	Divergence *LockstepDivergence
+	// the fault both contexts agree on or the error of the cancelled Go context
+	Err error
+}
+
+// RunLockstep steps two contexts that start with the same program, for example with different memory maps
+// or devices, and stops at the first step after which they differ. Both contexts have to fault the same way,
+// which ends the run with SR_Fault too. Observers of the contexts keep working during the run.
+func RunLockstep(ctx gocontext.Context, first *Context, second *Context, options LockstepOptions) LockstepResult {
+	if options.HistorySize == 0 {
+		options.HistorySize = 16
+	}
+
+	contexts := [2]*Context{first, second}
+	recorders := [2]*writeRecorder{{}, {}}
+	for i, context := range contexts {
+		previous := context.Observer
+		context.AddObserver(recorders[i])
+		defer func(context *Context) { context.Observer = previous }(context)
+	}
+
+	history := make([]string, 0, options.HistorySize)
+	result := LockstepResult{}
+	for {
+		if first.haltedForever() && second.haltedForever() {
+			result.Reason = SR_Halted
+			return result
+		}
+		if options.MaxInstructions != 0 && result.Steps >= options.MaxInstructions {
+			result.Reason = SR_Limit
+			return result
+		}
+		if result.Steps%runCancelInterval == 0 && ctx.Err() != nil {
+			result.Reason = SR_Cancelled
+			result.Err = ctx.Err()
+			return result
+		}
+
+		address := first.InstructionAddress()
+		states := [2]LockstepState{}
+		for i, context := range contexts {
+			recorders[i].writes = recorders[i].writes[:0]
+			instruction, err := Step(context)
+			states[i] = lockstepState(context, instruction, recorders[i].writes, err)
+		}
+
+		difference := options.difference(states[0], states[1])
+		if difference != "" {
+			result.Reason = SR_Fault
+			result.Divergence = &LockstepDivergence{Step: result.Steps, Difference: difference, States: states, History: history}
+			result.Err = result.Divergence
+			return result
+		}
+		if states[0].Err != nil {
+			result.Reason = SR_Fault
+			result.Err = states[0].Err
+			return result
+		}
+
+		if len(history) == options.HistorySize {
+			history = history[1:]
+		}
+		history = append(history, fmt.Sprintf("0x%05x: %s", address, states[0].Instruction))
+		result.Steps++
+	}
+}
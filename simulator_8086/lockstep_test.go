@@ -0,0 +1,54 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newLockstepContext() *Context {
+	context := &Context{}
+	copy(context.Memory[0x100:], []byte{
+		0xb8, 0x34, 0x12, // mov ax, 0x1234
+		0xa3, 0x00, 0x20, // mov [0x2000], ax
+		0x8b, 0x1e, 0x00, 0x20, // mov bx, [0x2000]
+		0xf4, // hlt
+	})
+	context.InstructionPointer = 0x100
+	return context
+}
+
+func TestLockstepEqual(t *testing.T) {
+	first, second := newLockstepContext(), newLockstepContext()
+	observer := &recordingObserver{}
+	first.AddObserver(observer)
+
+	result := RunLockstep(gocontext.Background(), first, second, LockstepOptions{CompareClocks: true})
+	require.Equal(t, SR_Halted, result.Reason)
+	require.Equal(t, uint64(4), result.Steps)
+	require.Nil(t, result.Divergence)
+	require.NotEmpty(t, observer.events)
+	require.Equal(t, observer, first.Observer)
+}
+
+func TestLockstepDivergence(t *testing.T) {
+	first, second := newLockstepContext(), newLockstepContext()
+	second.MemoryMap = NewMemoryMap()
+	require.NoError(t, second.MemoryMap.MapROM(0x2000, 0x10, false))
+
+	result := RunLockstep(gocontext.Background(), first, second, LockstepOptions{HistorySize: 1})
+	require.Equal(t, SR_Fault, result.Reason)
+	divergence := result.Divergence
+	require.Equal(t, uint64(2), divergence.Step)
+	require.Equal(t, "registers", divergence.Difference)
+	require.Equal(t, []string{"0x00103: mov word [8192], ax"}, divergence.History)
+	require.Equal(t, "mov bx, word [8192]", divergence.States[0].Instruction)
+
+	report := &strings.Builder{}
+	require.NoError(t, divergence.WriteReport(report))
+	require.Contains(t, report.String(), "lockstep diverged in registers at step 2\nlast 1 instructions:\n  0x00103: mov word [8192], ax\n")
+	require.Contains(t, report.String(), "bx: 0x1234")
+	require.Contains(t, report.String(), "bx: 0x0000")
+}
@@ -0,0 +1,143 @@
+package simulator8086
+
+import (
+	"fmt"
+)
+
+type MemoryRegionType int
+
+const (
+	MR_RAM MemoryRegionType = iota
+	MR_ROM
+	MR_Device
+	MR_Unmapped
+)
+
+// regions are mapped with a granularity of one paragraph
+const memoryMapGranularity = 16
+
+type MemoryDevice interface {
+	ReadMemory(offset int) byte
+	WriteMemory(offset int, value byte)
+}
+
+type MemoryRegion struct {
+	Start  int
+	Size   int
+	Type   MemoryRegionType
+	Device MemoryDevice
+
+	// writes to ROM are ignored, unless FaultOnWrite is set
+	FaultOnWrite bool
+}
+
+type MemoryFault struct {
+	Address int
+	Value   byte
+}
+
+func (f *MemoryFault) Error() string {
+	return fmt.Sprintf("write of 0x%02x to read-only memory at 0x%05x", f.Value, f.Address)
+}
+
+// MemoryMap routes accesses to the physical address space, RAM and ROM are backed by Context.Memory.
+// Everything that has not been mapped explicitly is RAM.
+type MemoryMap struct {
+	regions []MemoryRegion
+	// index into regions + 1 for every paragraph, 0 is RAM
+	lookup [MemorySize / memoryMapGranularity]uint16
+}
+
+func NewMemoryMap() *MemoryMap {
+	return &MemoryMap{}
+}
+
+func (m *MemoryMap) Map(region MemoryRegion) error {
+	if region.Start%memoryMapGranularity != 0 || region.Size%memoryMapGranularity != 0 {
+		return fmt.Errorf("memory region 0x%05x+0x%x is not aligned to %d bytes", region.Start, region.Size, memoryMapGranularity)
+	}
+	if region.Start < 0 || region.Size <= 0 || region.Start+region.Size > MemorySize {
+		return fmt.Errorf("memory region 0x%05x+0x%x is outside of the address space", region.Start, region.Size)
+	}
+	if region.Type == MR_Device && region.Device == nil {
+		return fmt.Errorf("memory region 0x%05x+0x%x has no device", region.Start, region.Size)
+	}
+
+	m.regions = append(m.regions, region)
+	index := uint16(len(m.regions))
+	for paragraph := region.Start / memoryMapGranularity; paragraph < (region.Start+region.Size)/memoryMapGranularity; paragraph++ {
+		m.lookup[paragraph] = index
+	}
+	return nil
+}
+
+func (m *MemoryMap) MapRAM(start int, size int) error {
+	return m.Map(MemoryRegion{Start: start, Size: size, Type: MR_RAM})
+}
+
+func (m *MemoryMap) MapROM(start int, size int, faultOnWrite bool) error {
+	return m.Map(MemoryRegion{Start: start, Size: size, Type: MR_ROM, FaultOnWrite: faultOnWrite})
+}
+
+func (m *MemoryMap) MapDevice(start int, size int, device MemoryDevice) error {
+	return m.Map(MemoryRegion{Start: start, Size: size, Type: MR_Device, Device: device})
+}
+
+func (m *MemoryMap) Unmap(start int, size int) error {
+	return m.Map(MemoryRegion{Start: start, Size: size, Type: MR_Unmapped})
+}
+
+// Region returns the region an address belongs to, ok is false for plain RAM
+func (m *MemoryMap) Region(address int) (MemoryRegion, bool) {
+	index := m.lookup[address/memoryMapGranularity]
+	if index == 0 {
+		return MemoryRegion{}, false
+	}
+	return m.regions[index-1], true
+}
+
+// isMemory tells whether the byte at address is RAM or ROM, which only change by writes to Context.Memory
+func (m *MemoryMap) isMemory(address int) bool {
+	index := m.lookup[address/memoryMapGranularity]
+	return index == 0 || m.regions[index-1].Type == MR_RAM || m.regions[index-1].Type == MR_ROM
+}
+
+func (m *MemoryMap) read(context *Context, address int) byte {
+	index := m.lookup[address/memoryMapGranularity]
+	if index == 0 {
+		return context.Memory[address]
+	}
+
+	region := &m.regions[index-1]
+	switch region.Type {
+	case MR_Device:
+		return region.Device.ReadMemory(address - region.Start)
+	case MR_Unmapped:
+		// nothing drives the data bus
+		return 0xff
+	}
+	return context.Memory[address]
+}
+
+// write returns true if the write goes to Context.Memory
+func (m *MemoryMap) write(context *Context, address int, value byte) bool {
+	index := m.lookup[address/memoryMapGranularity]
+	if index == 0 {
+		return true
+	}
+
+	region := &m.regions[index-1]
+	switch region.Type {
+	case MR_ROM:
+		if region.FaultOnWrite && context.fault == nil {
+			context.fault = &MemoryFault{Address: address, Value: value}
+		}
+		return false
+	case MR_Device:
+		region.Device.WriteMemory(address-region.Start, value)
+		return false
+	case MR_Unmapped:
+		return false
+	}
+	return true
+}
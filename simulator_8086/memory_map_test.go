@@ -0,0 +1,118 @@
+package simulator8086
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testMemoryDevice struct {
+	reads  []int
+	writes map[int]byte
+}
+
+func (d *testMemoryDevice) ReadMemory(offset int) byte {
+	d.reads = append(d.reads, offset)
+	return byte(offset)
+}
+
+func (d *testMemoryDevice) WriteMemory(offset int, value byte) {
+	d.writes[offset] = value
+}
+
+func TestMemoryMap(t *testing.T) {
+	context := &Context{}
+	context.MemoryMap = NewMemoryMap()
+	device := &testMemoryDevice{writes: make(map[int]byte)}
+	require.NoError(t, context.MemoryMap.MapROM(0xf0000, 0x10000, false))
+	require.NoError(t, context.MemoryMap.MapROM(0xe0000, 0x10, true))
+	require.NoError(t, context.MemoryMap.MapDevice(0xb8000, 0x4000, device))
+	require.NoError(t, context.MemoryMap.Unmap(0xa0000, 0x10000))
+	require.Error(t, context.MemoryMap.MapRAM(0x12345, 0x10))
+
+	context.Memory[0xf0000] = 0x42
+	context.WriteMemory8(0xf0000, 0x12)
+	require.Equal(t, byte(0x42), context.ReadMemory8(0xf0000))
+
+	context.WriteMemory16(0xb8002, 0x0741)
+	require.Equal(t, map[int]byte{2: 0x41, 3: 0x07}, device.writes)
+	require.Equal(t, uint16(0x0504), context.ReadMemory16(0xb8004))
+	require.Equal(t, []int{4, 5}, device.reads)
+
+	context.WriteMemory8(0xa0000, 0x12)
+	require.Equal(t, byte(0xff), context.ReadMemory8(0xa0000))
+	require.Equal(t, byte(0), context.Memory[0xa0000])
+
+	region, ok := context.MemoryMap.Region(0xb8fff)
+	require.True(t, ok)
+	require.Equal(t, MR_Device, region.Type)
+	_, ok = context.MemoryMap.Region(0x100)
+	require.False(t, ok)
+}
+
+func TestMemoryMapDeviceReadsOnce(t *testing.T) {
+	context := &Context{}
+	context.MemoryMap = NewMemoryMap()
+	device := &testMemoryDevice{writes: make(map[int]byte)}
+	require.NoError(t, context.MemoryMap.MapDevice(0xb8000, 0x4000, device))
+	copy(context.Memory[:], []byte{
+		0xa1, 0x04, 0x00, // mov ax, [4]
+		0xa0, 0x06, 0x00, // mov al, [6]
+	})
+	context.SetRegister(DS, -0x4800) // b800h
+
+	// every byte of the device is read once, reads of registers may have side effects
+	_, err := Step(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(0x0504), context.GetRegister(AX))
+	require.Equal(t, []int{4, 5}, device.reads)
+	_, err = Step(context)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 5, 6}, device.reads)
+}
+
+func TestMemoryMapFetchAndFault(t *testing.T) {
+	context := &Context{}
+	context.MemoryMap = NewMemoryMap()
+	require.NoError(t, context.MemoryMap.MapROM(0xf0000, 0x10000, true))
+
+	program := []byte{
+		0xb8, 0x05, 0x00, // mov ax, 5
+		0xa3, 0x00, 0x00, // mov [0], ax
+	}
+	copy(context.Memory[0xf0000:], program)
+	context.SetRegister(CS, -0x1000)
+	context.SetRegister(DS, -0x1000)
+
+	_, err := Step(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(5), context.GetRegister(AX))
+
+	_, err = Step(context)
+	require.Equal(t, &MemoryFault{Address: 0xf0000, Value: 5}, err)
+	require.Equal(t, int16(3), context.InstructionPointer)
+	require.Equal(t, byte(0xb8), context.Memory[0xf0000])
+}
+
+func TestMemoryMapFaultUndoesInstruction(t *testing.T) {
+	context := &Context{}
+	context.MemoryMap = NewMemoryMap()
+	require.NoError(t, context.MemoryMap.MapROM(0x1000, 0x10, true))
+	copy(context.Memory[:], []byte{
+		0xb8, 0x34, 0x12, // mov ax, 0x1234
+		0x50, // push ax
+	})
+	// the low byte of the pushed word goes to RAM, the high byte to ROM
+	context.SetRegister(SP, 0x1001)
+	context.Memory[0xfff] = 0xaa
+
+	_, err := Step(context)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = Step(context)
+		require.Equal(t, &MemoryFault{Address: 0x1000, Value: 0x12}, err)
+		require.Equal(t, int16(3), context.InstructionPointer)
+		require.Equal(t, int16(0x1001), context.GetRegister(SP))
+		require.Equal(t, byte(0xaa), context.Memory[0xfff])
+	}
+}
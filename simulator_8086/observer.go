@@ -0,0 +1,77 @@
+package simulator8086
+
+// Observer is notified about everything the simulator does. Memory accesses are reported with the value of
+// the segment register they use, their physical address, size in bytes and value. Interrupt vectors are accessed
+// through segment 0. Accesses through ReadMemory* and WriteMemory* are not reported, since they do not originate
+// from the simulated program.
+// AfterInstruction follows every BeforeInstruction, also when the instruction failed and was undone,
+// which Context.InstructionFailed tells.
+type Observer interface {
+	BeforeInstruction(context *Context, address int, instruction Instruction)
+	AfterInstruction(context *Context, address int, instruction Instruction)
+	RegisterWrite(context *Context, registerName RegisterName, value int16)
+	MemoryRead(context *Context, segment int16, address int, size int, value uint16)
+	MemoryWrite(context *Context, segment int16, address int, size int, value uint16)
+	Interrupt(context *Context, number byte)
+}
+
+// BaseObserver can be embedded to only implement the callbacks of interest
+type BaseObserver struct{}
+
+func (BaseObserver) BeforeInstruction(*Context, int, Instruction)  {}
+func (BaseObserver) AfterInstruction(*Context, int, Instruction)   {}
+func (BaseObserver) RegisterWrite(*Context, RegisterName, int16)   {}
+func (BaseObserver) MemoryRead(*Context, int16, int, int, uint16)  {}
+func (BaseObserver) MemoryWrite(*Context, int16, int, int, uint16) {}
+func (BaseObserver) Interrupt(*Context, byte)                      {}
+
+// MultiObserver forwards every callback to all of its observers in order
+type MultiObserver []Observer
+
+func (m MultiObserver) BeforeInstruction(context *Context, address int, instruction Instruction) {
+	for _, observer := range m {
+		observer.BeforeInstruction(context, address, instruction)
+	}
+}
+
+func (m MultiObserver) AfterInstruction(context *Context, address int, instruction Instruction) {
+	for _, observer := range m {
+		observer.AfterInstruction(context, address, instruction)
+	}
+}
+
+func (m MultiObserver) RegisterWrite(context *Context, registerName RegisterName, value int16) {
+	for _, observer := range m {
+		observer.RegisterWrite(context, registerName, value)
+	}
+}
+
+func (m MultiObserver) MemoryRead(context *Context, segment int16, address int, size int, value uint16) {
+	for _, observer := range m {
+		observer.MemoryRead(context, segment, address, size, value)
+	}
+}
+
+func (m MultiObserver) MemoryWrite(context *Context, segment int16, address int, size int, value uint16) {
+	for _, observer := range m {
+		observer.MemoryWrite(context, segment, address, size, value)
+	}
+}
+
+func (m MultiObserver) Interrupt(context *Context, number byte) {
+	for _, observer := range m {
+		observer.Interrupt(context, number)
+	}
+}
+
+// AddObserver attaches another observer without replacing the ones already attached
+func (c *Context) AddObserver(observer Observer) {
+	switch current := c.Observer.(type) {
+	case nil:
+		c.Observer = observer
+	case MultiObserver:
+		c.Observer = append(current, observer)
+	default:
+		c.Observer = MultiObserver{current, observer}
+	}
+}
@@ -0,0 +1,124 @@
+package simulator8086
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	BaseObserver
+	events []string
+}
+
+func (o *recordingObserver) BeforeInstruction(context *Context, address int, instruction Instruction) {
+	o.events = append(o.events, fmt.Sprintf("before %#x %s", address, instruction.Type.Name()))
+}
+
+func (o *recordingObserver) AfterInstruction(context *Context, address int, instruction Instruction) {
+	if context.InstructionFailed() {
+		o.events = append(o.events, fmt.Sprintf("failed %#x", address))
+		return
+	}
+	o.events = append(o.events, fmt.Sprintf("after %#x", address))
+}
+
+func (o *recordingObserver) RegisterWrite(context *Context, registerName RegisterName, value int16) {
+	o.events = append(o.events, fmt.Sprintf("register %s=%#x", registerName, value))
+}
+
+func (o *recordingObserver) MemoryRead(context *Context, segment int16, address int, size int, value uint16) {
+	o.events = append(o.events, fmt.Sprintf("read %#x/%d=%#x", address, size, value))
+}
+
+func (o *recordingObserver) MemoryWrite(context *Context, segment int16, address int, size int, value uint16) {
+	o.events = append(o.events, fmt.Sprintf("write %#x/%d=%#x", address, size, value))
+}
+
+func (o *recordingObserver) Interrupt(context *Context, number byte) {
+	o.events = append(o.events, fmt.Sprintf("interrupt %#x", number))
+}
+
+func TestObserver(t *testing.T) {
+	context := &Context{}
+	program := []byte{
+		0xb8, 0x05, 0x00, // mov ax, 5
+		0xa2, 0x00, 0x02, // mov [512], al
+		0xcd, 0x21, // int 0x21
+	}
+	copy(context.Memory[0x100:], program)
+	context.InstructionPointer = 0x100
+	context.SetRegister(SP, 0x1000)
+	context.WriteMemory16(0x21*4, 0x0300)
+	context.Memory[0x300] = 0xcf // iret
+
+	observer := &recordingObserver{}
+	context.AddObserver(observer)
+	for i := 0; i < 4; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []string{
+		"before 0x100 mov",
+		"register ax=0x5",
+		"after 0x100",
+		"before 0x103 mov",
+		"write 0x200/1=0x5",
+		"after 0x103",
+		"before 0x106 int",
+		"interrupt 0x21",
+		"register sp=0xffe",
+		"write 0xffe/2=0xf002",
+		"register sp=0xffc",
+		"write 0xffc/2=0x0",
+		"register sp=0xffa",
+		"write 0xffa/2=0x108",
+		"read 0x84/2=0x300",
+		"read 0x86/2=0x0",
+		"register cs=0x0",
+		"after 0x106",
+		"before 0x300 iret",
+		"read 0xffa/2=0x108",
+		"register sp=0xffc",
+		"read 0xffc/2=0x0",
+		"register sp=0xffe",
+		"register cs=0x0",
+		"read 0xffe/2=0xf002",
+		"register sp=0x1000",
+		"after 0x300",
+	}, observer.events)
+	require.Equal(t, int16(0x108), context.InstructionPointer)
+
+	second := &recordingObserver{}
+	context.AddObserver(second)
+	require.Equal(t, MultiObserver{observer, second}, context.Observer)
+}
+
+func TestObserverFailedInterruptHandler(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[0x100:], []byte{
+		0xcd, 0x21, // int 0x21
+	})
+	context.InstructionPointer = 0x100
+	context.SetRegister(AX, 0x4c00)
+	context.InterruptHandlers[0x21] = func(context *Context, number byte) error {
+		context.SetRegister(AX, 0)
+		return fmt.Errorf("unsupported function")
+	}
+	observer := &recordingObserver{}
+	context.AddObserver(observer)
+
+	_, err := Step(context)
+	require.EqualError(t, err, "unsupported function")
+	// like a fault the instruction is undone, the handler is called again by the next step
+	require.Equal(t, int16(0x100), context.InstructionPointer)
+	require.Equal(t, int16(0x4c00), context.GetRegister(AX))
+	require.Equal(t, []string{
+		"before 0x100 int",
+		"interrupt 0x21",
+		"register ax=0x0",
+		"failed 0x100",
+	}, observer.events)
+}
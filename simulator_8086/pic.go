@@ -0,0 +1,159 @@
+package simulator8086
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	PICPort      = 0x20
+	picPortCount = 2
+	// vector of IRQ 0 on the PC
+	picDefaultVectorBase = 0x08
+)
+
+type picState struct {
+	// interrupt request, in-service and mask registers, bit n belongs to IRQ n
+	Request    byte
+	InService  byte
+	Mask       byte
+	VectorBase byte
+	// the initialization command word expected next, 0 when initialized
+	InitializationStep byte
+	Single             bool
+	NeedsICW4          bool
+	AutoEOI            bool
+	// OCW3 selects whether the command port reads the in-service or the request register
+	ReadInService bool
+	Lines         byte
+}
+
+// PIC is an 8259 programmable interrupt controller with fixed priorities, IRQ 0 is the highest.
+// Interrupt requests are edge triggered. Until the program initializes it, IRQ n is delivered
+// as vector 08h+n like on the PC, but all lines are masked.
+type PIC struct {
+	state picState
+}
+
+func NewPIC() *PIC {
+	return &PIC{state: picState{VectorBase: picDefaultVectorBase, Mask: 0xff}}
+}
+
+// Attach maps the PIC to ports 20h and 21h and makes it the interrupt controller of the context
+func (p *PIC) Attach(context *Context) error {
+	context.InterruptController = p
+	return context.attachPorts(PICPort, picPortCount, p)
+}
+
+// RaiseIRQ requests an interrupt on a line, like a rising edge does
+func (p *PIC) RaiseIRQ(line int) {
+	p.state.Request |= 1 << line
+}
+
+// SetIRQ sets the level of a line, an interrupt is requested on the rising edge
+func (p *PIC) SetIRQ(line int, level bool) {
+	bit := byte(1 << line)
+	if level && p.state.Lines&bit == 0 {
+		p.state.Request |= bit
+	}
+	if level {
+		p.state.Lines |= bit
+	} else {
+		p.state.Lines &^= bit
+	}
+}
+
+func (p *PIC) Acknowledge() (byte, bool) {
+	pending := p.state.Request &^ p.state.Mask
+	for line := 0; line < 8; line++ {
+		bit := byte(1 << line)
+		if p.state.InService&bit != 0 {
+			// an interrupt of the same or a higher priority is being serviced
+			return 0, false
+		}
+		if pending&bit != 0 {
+			p.state.Request &^= bit
+			if !p.state.AutoEOI {
+				p.state.InService |= bit
+			}
+			return p.state.VectorBase + byte(line), true
+		}
+	}
+	return 0, false
+}
+
+func (p *PIC) ReadPort(offset int) byte {
+	if offset == 1 {
+		return p.state.Mask
+	}
+	if p.state.ReadInService {
+		return p.state.InService
+	}
+	return p.state.Request
+}
+
+func (p *PIC) WritePort(offset int, value byte) {
+	state := &p.state
+	if offset == 0 {
+		switch {
+		case value&0x10 != 0:
+			// ICW1 starts the initialization sequence
+			*state = picState{
+				VectorBase:         state.VectorBase,
+				Lines:              state.Lines,
+				InitializationStep: 2,
+				Single:             value&0x02 != 0,
+				NeedsICW4:          value&0x01 != 0,
+			}
+		case value&0x08 != 0:
+			// OCW3
+			if value&0x02 != 0 {
+				state.ReadInService = value&0x01 != 0
+			}
+		default:
+			// OCW2, only the non-specific and specific end of interrupt commands are supported
+			switch value >> 5 {
+			case 0b001:
+				state.InService &= state.InService - 1
+			case 0b011:
+				state.InService &^= 1 << (value & 0b111)
+			}
+		}
+		return
+	}
+
+	switch state.InitializationStep {
+	case 2:
+		state.VectorBase = value & 0xf8
+		state.InitializationStep = 3
+		if state.Single {
+			state.InitializationStep = 4
+		}
+	case 3:
+		// ICW3 describes the cascaded controllers, there is only one
+		state.InitializationStep = 4
+	case 4:
+		state.AutoEOI = value&0x02 != 0
+		state.InitializationStep = 0
+	default:
+		state.Mask = value
+		return
+	}
+	if state.InitializationStep == 4 && !state.NeedsICW4 {
+		state.InitializationStep = 0
+	}
+}
+
+func (p *PIC) DeviceName() string {
+	return "pic"
+}
+
+func (p *PIC) SaveState() ([]byte, error) {
+	buffer := bytes.Buffer{}
+	err := binary.Write(&buffer, binary.LittleEndian, p.state)
+	return buffer.Bytes(), err
+}
+
+func (p *PIC) LoadState(state []byte) error {
+	return binary.Read(bytes.NewReader(state), binary.LittleEndian, &p.state)
+}
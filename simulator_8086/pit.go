@@ -0,0 +1,241 @@
+package simulator8086
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	PITPort      = 0x40
+	pitPortCount = 4
+	// the PIT runs at 1.193182 MHz, a quarter of the 4.77 MHz CPU clock of the PC
+	pitClockDivider = 4
+	pitChannels     = 3
+)
+
+// access modes of the control word
+const (
+	pitLatch    = 0b00
+	pitLowByte  = 0b01
+	pitHighByte = 0b10
+	pitLowHigh  = 0b11
+)
+
+type pitChannel struct {
+	Mode       byte
+	AccessMode byte
+	// a reload value of 0 counts 65536 ticks
+	Reload   uint16
+	Count    uint32
+	Counting bool
+	Output   bool
+
+	Latch   uint16
+	Latched bool
+	// the next byte read or written in the low/high access mode is the high byte
+	ReadHigh  bool
+	WriteHigh bool
+	WriteLow  byte
+}
+
+type pitState struct {
+	Channels [pitChannels]pitChannel
+	// CPU clocks that did not make up a full PIT tick yet
+	Remainder uint32
+}
+
+// PIT is an 8253 programmable interval timer driven by the simulated clocks.
+// The output of channel 0 raises IRQ 0, channels 1 and 2 count but are not connected.
+// Modes 0, 2 and 3 are supported, the hardware triggered modes behave like mode 0 and
+// mode 3 like mode 2 apart from the shape of the output.
+type PIT struct {
+	PIC   *PIC
+	state pitState
+}
+
+func NewPIT(pic *PIC) *PIT {
+	return &PIT{PIC: pic}
+}
+
+// Attach maps the PIT to ports 40h to 43h and advances it with the clocks of the context
+func (p *PIT) Attach(context *Context) error {
+	context.ClockedDevices = append(context.ClockedDevices, p)
+	return context.attachPorts(PITPort, pitPortCount, p)
+}
+
+func (c *pitChannel) period() uint32 {
+	if c.Reload == 0 {
+		return 0x10000
+	}
+	return uint32(c.Reload)
+}
+
+func (c *pitChannel) currentCount() uint16 {
+	return uint16(c.Count)
+}
+
+func (p *PIT) ReadPort(offset int) byte {
+	if offset == 3 {
+		// the control word register can not be read
+		return 0xff
+	}
+
+	channel := &p.state.Channels[offset]
+	value := channel.currentCount()
+	if channel.Latched {
+		value = channel.Latch
+	}
+
+	high := channel.AccessMode == pitHighByte
+	if channel.AccessMode == pitLowHigh {
+		high = channel.ReadHigh
+		channel.ReadHigh = !channel.ReadHigh
+	}
+	if channel.Latched && (channel.AccessMode != pitLowHigh || high) {
+		channel.Latched = false
+	}
+
+	if high {
+		return byte(value >> 8)
+	}
+	return byte(value)
+}
+
+func (p *PIT) WritePort(offset int, value byte) {
+	if offset == 3 {
+		p.writeControlWord(value)
+		return
+	}
+
+	channel := &p.state.Channels[offset]
+	switch channel.AccessMode {
+	case pitLowByte:
+		channel.load(uint16(value))
+	case pitHighByte:
+		channel.load(uint16(value) << 8)
+	case pitLowHigh:
+		if !channel.WriteHigh {
+			channel.WriteLow = value
+			channel.WriteHigh = true
+			return
+		}
+		channel.WriteHigh = false
+		channel.load(uint16(value)<<8 | uint16(channel.WriteLow))
+	}
+}
+
+func (p *PIT) writeControlWord(value byte) {
+	index := value >> 6
+	if index >= pitChannels {
+		// read-back command of the 8254
+		return
+	}
+
+	channel := &p.state.Channels[index]
+	accessMode := (value >> 4) & 0b11
+	if accessMode == pitLatch {
+		if !channel.Latched {
+			channel.Latch = channel.currentCount()
+			channel.Latched = true
+		}
+		return
+	}
+
+	mode := (value >> 1) & 0b111
+	if mode >= 6 {
+		// modes 6 and 7 are aliases of 2 and 3
+		mode -= 4
+	}
+	*channel = pitChannel{
+		Mode:       mode,
+		AccessMode: accessMode,
+		// the output is low in mode 0 until the count expires and high in the other modes
+		Output: mode != 0,
+	}
+	if index == 0 {
+		p.setIRQ(channel.Output)
+	}
+}
+
+func (c *pitChannel) load(value uint16) {
+	c.Reload = value
+	c.Count = c.period()
+	c.Counting = true
+	if c.Mode == 0 {
+		c.Output = false
+	}
+}
+
+// advance counts down by ticks and returns whether the output had a rising edge
+func (c *pitChannel) advance(ticks uint32) bool {
+	if !c.Counting || ticks == 0 {
+		return false
+	}
+
+	switch c.Mode {
+	case 2, 3:
+		period := c.period()
+		rising := false
+		if ticks >= c.Count {
+			// the counter reloads whenever it reaches zero
+			ticks -= c.Count
+			c.Count = period - ticks%period
+			rising = true
+		} else {
+			c.Count -= ticks
+		}
+		c.Output = c.Mode == 2 || c.Count > period/2
+		return rising
+	default:
+		if !c.Output && ticks >= c.Count {
+			// the output stays high after the terminal count, while the counter wraps around
+			c.Count = 0x10000 - (ticks-c.Count)%0x10000
+			c.Output = true
+			return true
+		}
+		c.Count = (c.Count + 0x10000 - ticks%0x10000) % 0x10000
+		if c.Count == 0 {
+			c.Count = 0x10000
+		}
+		return false
+	}
+}
+
+func (p *PIT) setIRQ(level bool) {
+	if p.PIC != nil {
+		p.PIC.SetIRQ(0, level)
+	}
+}
+
+func (p *PIT) AdvanceClocks(clocks int) {
+	total := p.state.Remainder + uint32(clocks)
+	ticks := total / pitClockDivider
+	p.state.Remainder = total % pitClockDivider
+
+	for i := range p.state.Channels {
+		channel := &p.state.Channels[i]
+		rising := channel.advance(ticks)
+		if i != 0 {
+			continue
+		}
+		if rising {
+			// an edge is generated even if the output is high before and after the advance
+			p.setIRQ(false)
+		}
+		p.setIRQ(channel.Output)
+	}
+}
+
+func (p *PIT) DeviceName() string {
+	return "pit"
+}
+
+func (p *PIT) SaveState() ([]byte, error) {
+	buffer := bytes.Buffer{}
+	err := binary.Write(&buffer, binary.LittleEndian, p.state)
+	return buffer.Bytes(), err
+}
+
+func (p *PIT) LoadState(state []byte) error {
+	return binary.Read(bytes.NewReader(state), binary.LittleEndian, &p.state)
+}
@@ -0,0 +1,125 @@
+package simulator8086
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// loadTimerProgram sets up the PIC and channel 0 of the PIT to interrupt every 100 ticks and waits in a loop
+func loadTimerProgram(context *Context, handler []byte) {
+	copy(context.Memory[0x1000:], []byte{
+		0xb0, 0x13, // mov al, 13h
+		0xe6, 0x20, // out 20h, al
+		0xb0, 0x08, // mov al, 8
+		0xe6, 0x21, // out 21h, al
+		0xb0, 0x01, // mov al, 1
+		0xe6, 0x21, // out 21h, al
+		0xb0, 0xfe, // mov al, 0feh
+		0xe6, 0x21, // out 21h, al
+		0xb0, 0x34, // mov al, 34h
+		0xe6, 0x43, // out 43h, al
+		0xb0, 0x64, // mov al, 100
+		0xe6, 0x40, // out 40h, al
+		0xb0, 0x00, // mov al, 0
+		0xe6, 0x40, // out 40h, al
+		0xfb,       // sti
+		0xe3, 0xfe, // jcxz $
+	})
+	copy(context.Memory[0x2000:], handler)
+	context.WriteMemory16(8*4, 0x2000)
+	context.InstructionPointer = 0x1000
+	context.SetRegister(SP, 0x0800)
+}
+
+func TestTimerInterrupts(t *testing.T) {
+	context := &Context{}
+	pic := NewPIC()
+	require.NoError(t, pic.Attach(context))
+	require.NoError(t, NewPIT(pic).Attach(context))
+	loadTimerProgram(context, []byte{
+		0x83, 0x06, 0x00, 0x05, 0x01, // add word [500h], 1
+		0xb0, 0x20, // mov al, 20h
+		0xe6, 0x20, // out 20h, al
+		0xcf, // iret
+	})
+
+	interrupts := 0
+	observer := &recordingObserver{}
+	context.AddObserver(observer)
+	for i := 0; i < 20000; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	for _, event := range observer.events {
+		if event == "interrupt 0x8" {
+			interrupts++
+		}
+	}
+
+	// channel 0 counts 100 ticks of 4 clocks each
+	require.InDelta(t, float64(context.Clocks)/400, float64(interrupts), 2)
+	require.Equal(t, uint16(interrupts), context.ReadMemory16(0x500))
+	require.Equal(t, int16(0x0800), context.GetRegister(SP))
+}
+
+func TestPITCounterLatch(t *testing.T) {
+	pit := NewPIT(nil)
+	pit.WritePort(3, 0b10110100) // channel 2, low/high byte, mode 2
+	pit.WritePort(2, 0x00)
+	pit.WritePort(2, 0x10)
+	pit.AdvanceClocks(4 * 0x100)
+
+	pit.WritePort(3, 0b10000000) // latch channel 2
+	pit.AdvanceClocks(4 * 0x10)
+	require.Equal(t, byte(0x00), pit.ReadPort(2))
+	require.Equal(t, byte(0x0f), pit.ReadPort(2))
+	require.Equal(t, byte(0xf0), pit.ReadPort(2))
+	require.Equal(t, byte(0x0e), pit.ReadPort(2))
+}
+
+func TestPICPriorities(t *testing.T) {
+	pic := NewPIC()
+	pic.WritePort(0, 0x13)
+	pic.WritePort(1, 0x20)
+	pic.WritePort(1, 0x01)
+	pic.WritePort(1, 0b11111000) // IRQ 0 to 2 enabled
+
+	_, ok := pic.Acknowledge()
+	require.False(t, ok)
+
+	pic.RaiseIRQ(3)
+	pic.RaiseIRQ(2)
+	pic.RaiseIRQ(1)
+	vector, ok := pic.Acknowledge()
+	require.True(t, ok)
+	require.Equal(t, byte(0x21), vector)
+
+	// IRQ 2 has a lower priority than the one in service
+	_, ok = pic.Acknowledge()
+	require.False(t, ok)
+	pic.RaiseIRQ(0)
+	vector, _ = pic.Acknowledge()
+	require.Equal(t, byte(0x20), vector)
+
+	pic.WritePort(0, 0x0b)
+	require.Equal(t, byte(0b11), pic.ReadPort(0))
+	pic.WritePort(0, 0x20)
+	pic.WritePort(0, 0x20)
+	require.Equal(t, byte(0), pic.ReadPort(0))
+
+	vector, _ = pic.Acknowledge()
+	require.Equal(t, byte(0x22), vector)
+	pic.WritePort(0, 0x62)
+	// IRQ 3 is masked
+	_, ok = pic.Acknowledge()
+	require.False(t, ok)
+	pic.WritePort(0, 0x0a)
+	require.Equal(t, byte(0b1000), pic.ReadPort(0))
+
+	state, err := pic.SaveState()
+	require.NoError(t, err)
+	restored := NewPIC()
+	require.NoError(t, restored.LoadState(state))
+	require.Equal(t, pic.state, restored.state)
+}
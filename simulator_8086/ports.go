@@ -0,0 +1,147 @@
+package simulator8086
+
+import (
+	"fmt"
+	"io"
+)
+
+const PortCount = 0x10000
+
+// PortDevice is a device in the I/O address space, offset is relative to the first port of the device
+type PortDevice interface {
+	ReadPort(offset int) byte
+	WritePort(offset int, value byte)
+}
+
+type portRange struct {
+	start  int
+	device PortDevice
+}
+
+// PortMap routes IN and OUT instructions to devices, unmapped ports read as 0xff and ignore writes
+type PortMap struct {
+	ranges []portRange
+	// index into ranges + 1 for every port, 0 is unmapped
+	lookup [PortCount]uint16
+}
+
+func NewPortMap() *PortMap {
+	return &PortMap{}
+}
+
+func (m *PortMap) Map(start int, count int, device PortDevice) error {
+	if start < 0 || count <= 0 || start+count > PortCount {
+		return fmt.Errorf("ports 0x%04x+0x%x are outside of the I/O address space", start, count)
+	}
+	if device == nil {
+		return fmt.Errorf("ports 0x%04x+0x%x have no device", start, count)
+	}
+
+	m.ranges = append(m.ranges, portRange{start: start, device: device})
+	index := uint16(len(m.ranges))
+	for port := start; port < start+count; port++ {
+		m.lookup[port] = index
+	}
+	return nil
+}
+
+func (m *PortMap) read(port uint16) byte {
+	index := m.lookup[port]
+	if index == 0 {
+		return 0xff
+	}
+	r := &m.ranges[index-1]
+	return r.device.ReadPort(int(port) - r.start)
+}
+
+func (m *PortMap) write(port uint16, value byte) {
+	index := m.lookup[port]
+	if index == 0 {
+		return
+	}
+	r := &m.ranges[index-1]
+	r.device.WritePort(int(port)-r.start, value)
+}
+
+func (c *Context) ReadPort(port uint16) byte {
+	if c.Ports == nil {
+		return 0xff
+	}
+	return c.Ports.read(port)
+}
+
+func (c *Context) WritePort(port uint16, value byte) {
+	if c.Ports != nil {
+		c.Ports.write(port, value)
+	}
+}
+
+// attachPorts maps a device, a port map is created if the context does not have one yet
+func (c *Context) attachPorts(start int, count int, device PortDevice) error {
+	if c.Ports == nil {
+		c.Ports = NewPortMap()
+	}
+	return c.Ports.Map(start, count, device)
+}
+
+// ClockedDevice is advanced by the number of clocks every simulated instruction takes
+type ClockedDevice interface {
+	AdvanceClocks(clocks int)
+}
+
+// InterruptController delivers hardware interrupts to the CPU
+type InterruptController interface {
+	// Acknowledge returns the vector of the highest priority pending interrupt
+	Acknowledge() (byte, bool)
+}
+
+func (c *Context) advanceClocks(clocks int) {
+	c.Clocks += uint64(clocks)
+	for _, device := range c.ClockedDevices {
+		device.AdvanceClocks(clocks)
+	}
+}
+
+// readInputPort is the port read of an IN instruction, it goes through the input log if there is one
+func (c *Context) readInputPort(port uint16) byte {
+	if c.InputLog == nil {
+		return c.ReadPort(port)
+	}
+	value, err := c.InputLog.portRead(c, port)
+	if err != nil && c.fault == nil {
+		c.fault = err
+	}
+	return value
+}
+
+// readInput is a read from the host by a Go interrupt handler, like the console input of DOS,
+// it goes through the input log if there is one
+func (c *Context) readInput(reader io.Reader, buffer []byte) (int, error) {
+	if c.InputLog == nil {
+		return reader.Read(buffer)
+	}
+	return c.InputLog.read(c, reader, buffer)
+}
+
+// checkInterrupts enters the handler of a pending hardware interrupt if interrupts are enabled
+func (c *Context) checkInterrupts() error {
+	vector, ok := byte(0), false
+	if c.InputLog != nil {
+		var err error
+		vector, ok, err = c.InputLog.interrupt(c)
+		if err != nil {
+			return err
+		}
+	} else if c.InterruptController != nil && c.GetFlag(Flag_Interrupt) {
+		vector, ok = c.InterruptController.Acknowledge()
+	}
+	if !ok {
+		return nil
+	}
+	if c.History != nil {
+		c.History.beginStep(c)
+	}
+	c.Halted = false
+	c.advanceClocks(interruptAcknowledgeClocks)
+	return c.Interrupt(vector)
+}
@@ -0,0 +1,282 @@
+package simulator8086
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ProfileSort int
+
+const (
+	PS_Inclusive ProfileSort = iota
+	PS_Exclusive
+)
+
+// ProfileEntry is the time spent in a function or below a label, like TimeAggregate of the haversine profiler
+type ProfileEntry struct {
+	Label   string
+	Address int
+	// calls of a function, executed instructions below a label
+	HitCount              uint64
+	ClocksWithoutChildren uint64
+	ClocksWithChildren    uint64
+}
+
+type profileFrame struct {
+	function        int
+	start           uint64
+	oldWithChildren uint64
+	interrupt       bool
+}
+
+// Profiler accumulates the estimated clocks of every executed instruction. Every CALL target and
+// interrupt handler is a function, the clocks of a function include the ones of the functions it calls.
+type Profiler struct {
+	BaseObserver
+	// names of physical addresses, used for functions and to aggregate the addresses following a label
+	Symbols map[int]string
+
+	Hits        map[int]uint64
+	Clocks      map[int]uint64
+	TotalClocks uint64
+
+	functions        map[int]*ProfileEntry
+	stack            []profileFrame
+	pendingInterrupt bool
+}
+
+func NewProfiler(symbols map[int]string) *Profiler {
+	if symbols == nil {
+		symbols = map[int]string{}
+	}
+	return &Profiler{
+		Symbols:   symbols,
+		Hits:      map[int]uint64{},
+		Clocks:    map[int]uint64{},
+		functions: map[int]*ProfileEntry{},
+	}
+}
+
+func (p *Profiler) functionName(address int) string {
+	name, ok := p.Symbols[address]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("sub_%05x", address)
+}
+
+func (p *Profiler) function(address int) *ProfileEntry {
+	entry, ok := p.functions[address]
+	if !ok {
+		entry = &ProfileEntry{Label: p.functionName(address), Address: address}
+		p.functions[address] = entry
+	}
+	return entry
+}
+
+func (p *Profiler) enter(address int, interrupt bool) {
+	p.stack = append(p.stack, profileFrame{
+		function:        address,
+		start:           p.TotalClocks,
+		oldWithChildren: p.function(address).ClocksWithChildren,
+		interrupt:       interrupt,
+	})
+}
+
+// closeFrame adds the clocks since the frame was entered to its function, the parent does not get them
+// as its own clocks. The subtraction may wrap around temporarily, like in TimeAggregate.
+func closeFrame(functions map[int]*ProfileEntry, frame profileFrame, parent *profileFrame, totalClocks uint64) {
+	elapsed := totalClocks - frame.start
+	if parent != nil {
+		functions[parent.function].ClocksWithoutChildren -= elapsed
+	}
+	entry := functions[frame.function]
+	entry.ClocksWithoutChildren += elapsed
+	entry.ClocksWithChildren = frame.oldWithChildren + elapsed
+	entry.HitCount++
+}
+
+func (p *Profiler) leave(interrupt bool) {
+	// the function the profiling started in is never left
+	if len(p.stack) <= 1 || p.stack[len(p.stack)-1].interrupt != interrupt {
+		return
+	}
+	frame := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	closeFrame(p.functions, frame, &p.stack[len(p.stack)-1], p.TotalClocks)
+}
+
+func isCall(t InstructionType) bool {
+	switch t {
+	case IT_CallDirectWithinSegment, IT_CallIndirectWithinSegment, IT_CallDirectIntersegment, IT_CallIndirectIntersegment:
+		return true
+	}
+	return false
+}
+
+func isReturn(t InstructionType) bool {
+	switch t {
+	case IT_ReturnWithinSegment, IT_ReturnWithinSegmentAddingImmediateToSP,
+		IT_ReturnIntersegment, IT_ReturnIntersegmentAddingImmediateToSP:
+		return true
+	}
+	return false
+}
+
+func (p *Profiler) BeforeInstruction(context *Context, address int, instruction Instruction) {
+	if len(p.stack) == 0 || p.pendingInterrupt {
+		p.enter(address, p.pendingInterrupt)
+		p.pendingInterrupt = false
+	}
+}
+
+func (p *Profiler) AfterInstruction(context *Context, address int, instruction Instruction) {
+	if context.InstructionFailed() {
+		return
+	}
+	next := context.InstructionAddress()
+	clocks := uint64(InstructionClocks(context, instruction, next != (address+instruction.SizeInBytes)%MemorySize))
+	p.Hits[address]++
+	p.Clocks[address] += clocks
+	p.TotalClocks += clocks
+
+	switch {
+	case isCall(instruction.Type):
+		p.enter(next, false)
+	case isReturn(instruction.Type):
+		p.leave(false)
+	case instruction.Type == IT_InterruptReturn:
+		p.leave(true)
+	}
+}
+
+func (p *Profiler) Interrupt(context *Context, number byte) {
+	// handlers implemented in Go do not execute any instructions
+	if context.InterruptHandlers[number] == nil {
+		p.pendingInterrupt = true
+	}
+}
+
+func sortProfileEntries(entries []ProfileEntry, sortBy ProfileSort) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if sortBy == PS_Inclusive && a.ClocksWithChildren != b.ClocksWithChildren {
+			return a.ClocksWithChildren > b.ClocksWithChildren
+		}
+		if a.ClocksWithoutChildren != b.ClocksWithoutChildren {
+			return a.ClocksWithoutChildren > b.ClocksWithoutChildren
+		}
+		return a.Address < b.Address
+	})
+}
+
+// Functions returns the clocks per function, functions that have not returned yet count up to now
+func (p *Profiler) Functions(sortBy ProfileSort) []ProfileEntry {
+	functions := make(map[int]*ProfileEntry, len(p.functions))
+	for address, entry := range p.functions {
+		copied := *entry
+		functions[address] = &copied
+	}
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		var parent *profileFrame
+		if i > 0 {
+			parent = &p.stack[i-1]
+		}
+		closeFrame(functions, p.stack[i], parent, p.TotalClocks)
+	}
+
+	result := make([]ProfileEntry, 0, len(functions))
+	for _, entry := range functions {
+		result = append(result, *entry)
+	}
+	sortProfileEntries(result, sortBy)
+	return result
+}
+
+// Labels returns the clocks of the addresses from every symbol up to the next one, there are no children
+func (p *Profiler) Labels(sortBy ProfileSort) []ProfileEntry {
+	starts := make([]int, 0, len(p.Symbols))
+	for address := range p.Symbols {
+		starts = append(starts, address)
+	}
+	sort.Ints(starts)
+
+	labels := map[int]*ProfileEntry{}
+	for address, hits := range p.Hits {
+		// the last symbol at or before the address
+		index := sort.Search(len(starts), func(i int) bool { return starts[i] > address }) - 1
+		start, name := -1, "(no label)"
+		if index >= 0 {
+			start, name = starts[index], p.Symbols[starts[index]]
+		}
+		entry, ok := labels[start]
+		if !ok {
+			entry = &ProfileEntry{Label: name, Address: start}
+			labels[start] = entry
+		}
+		entry.HitCount += hits
+		entry.ClocksWithoutChildren += p.Clocks[address]
+		entry.ClocksWithChildren += p.Clocks[address]
+	}
+
+	result := make([]ProfileEntry, 0, len(labels))
+	for _, entry := range labels {
+		result = append(result, *entry)
+	}
+	sortProfileEntries(result, sortBy)
+	return result
+}
+
+func writeProfileEntries(writer *bufio.Writer, entries []ProfileEntry, totalClocks uint64) {
+	fmt.Fprintf(writer, "%-35s%10s%14s%9s Percent with Children\n", "Name", "Hits", "Clocks", "Percent")
+	total := float64(totalClocks)
+	for _, entry := range entries {
+		if entry.HitCount == 0 {
+			continue
+		}
+		percentage := float64(entry.ClocksWithoutChildren) / total * 100.0
+		fmt.Fprintf(writer, "%-35s%10d%14d %7.2f%% ", entry.Label, entry.HitCount, entry.ClocksWithoutChildren, percentage)
+		if entry.ClocksWithChildren != entry.ClocksWithoutChildren {
+			fmt.Fprintf(writer, "%6.2f%% ", float64(entry.ClocksWithChildren)/total*100.0)
+		}
+		fmt.Fprintln(writer)
+	}
+	fmt.Fprintf(writer, "%-45s%14d clocks\n", "Total", totalClocks)
+}
+
+// WriteReport prints the functions and, if there are symbols, the labels in the format of the haversine profiler
+func (p *Profiler) WriteReport(writer io.Writer, sortBy ProfileSort) error {
+	buffered := bufio.NewWriter(writer)
+	writeProfileEntries(buffered, p.Functions(sortBy), p.TotalClocks)
+	if len(p.Symbols) != 0 {
+		fmt.Fprintln(buffered)
+		writeProfileEntries(buffered, p.Labels(sortBy), p.TotalClocks)
+	}
+	return buffered.Flush()
+}
+
+// ParseSymbols reads lines of a hexadecimal physical address followed by a name, # starts a comment
+func ParseSymbols(reader io.Reader) (map[int]string, error) {
+	symbols := map[int]string{}
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected an address and a name", line)
+		}
+		address, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 20)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		symbols[int(address)] = fields[1]
+	}
+	return symbols, scanner.Err()
+}
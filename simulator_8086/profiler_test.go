@@ -0,0 +1,91 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadCallProgram(context *Context) {
+	copy(context.Memory[:], []byte{
+		0xe8, 0x07, 0x00, // call f
+		0xe8, 0x04, 0x00, // call f
+		0xf4, // hlt
+		0x90, 0x90, 0x90,
+		0xb8, 0x01, 0x00, // f: mov ax, 1
+		0xe8, 0x01, 0x00, // call g
+		0xc3,             // ret
+		0xbb, 0x02, 0x00, // g: mov bx, 2
+		0xc3, // ret
+	})
+	context.SetRegister(SP, 0x1000)
+}
+
+func TestProfilerFunctions(t *testing.T) {
+	context := &Context{}
+	loadCallProgram(context)
+	profiler := NewProfiler(map[int]string{0x00: "main", 0x0a: "f"})
+	context.AddObserver(profiler)
+
+	result := Run(gocontext.Background(), context, RunOptions{})
+	require.Equal(t, SR_Halted, result.Reason)
+	require.Equal(t, result.Clocks, profiler.TotalClocks)
+	require.Equal(t, uint64(126), profiler.TotalClocks)
+	require.Equal(t, uint64(2), profiler.Hits[0x0a])
+	require.Equal(t, uint64(38), profiler.Clocks[0x0d])
+
+	require.Equal(t, []ProfileEntry{
+		{Label: "main", Address: 0x00, HitCount: 1, ClocksWithoutChildren: 40, ClocksWithChildren: 126},
+		{Label: "f", Address: 0x0a, HitCount: 2, ClocksWithoutChildren: 62, ClocksWithChildren: 86},
+		{Label: "sub_00011", Address: 0x11, HitCount: 2, ClocksWithoutChildren: 24, ClocksWithChildren: 24},
+	}, profiler.Functions(PS_Inclusive))
+	require.Equal(t, "f", profiler.Functions(PS_Exclusive)[0].Label)
+
+	require.Equal(t, []ProfileEntry{
+		{Label: "f", Address: 0x0a, HitCount: 10, ClocksWithoutChildren: 86, ClocksWithChildren: 86},
+		{Label: "main", Address: 0x00, HitCount: 3, ClocksWithoutChildren: 40, ClocksWithChildren: 40},
+	}, profiler.Labels(PS_Inclusive))
+
+	report := &strings.Builder{}
+	require.NoError(t, profiler.WriteReport(report, PS_Inclusive))
+	lines := strings.Split(report.String(), "\n")
+	require.Equal(t, "Name                                     Hits        Clocks  Percent Percent with Children", lines[0])
+	require.Equal(t, "main                                        1            40   31.75% 100.00% ", lines[1])
+	require.Equal(t, "sub_00011                                   2            24   19.05% ", lines[3])
+	require.Equal(t, "Total                                                   126 clocks", lines[4])
+}
+
+func TestProfilerInterruptHandlers(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[0x1000:], []byte{
+		0xcd, 0x40, // int 40h
+		0xf4, // hlt
+	})
+	copy(context.Memory[0x200:], []byte{
+		0xbb, 0x02, 0x00, // mov bx, 2
+		0xcf, // iret
+	})
+	context.WriteMemory16(0x40*4, 0x200)
+	context.InstructionPointer = 0x1000
+	context.SetRegister(SP, 0x800)
+
+	profiler := NewProfiler(nil)
+	context.AddObserver(profiler)
+	Run(gocontext.Background(), context, RunOptions{})
+
+	functions := profiler.Functions(PS_Inclusive)
+	require.Len(t, functions, 2)
+	require.Equal(t, ProfileEntry{Label: "sub_00200", Address: 0x200, HitCount: 1, ClocksWithoutChildren: 28, ClocksWithChildren: 28}, functions[1])
+	require.Equal(t, uint64(51+2), functions[0].ClocksWithoutChildren)
+}
+
+func TestParseSymbols(t *testing.T) {
+	symbols, err := ParseSymbols(strings.NewReader("# symbols\n7c00 start\n0x7c3e print\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[int]string{0x7c00: "start", 0x7c3e: "print"}, symbols)
+
+	_, err = ParseSymbols(strings.NewReader("start\n"))
+	require.Error(t, err)
+}
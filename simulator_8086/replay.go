@@ -0,0 +1,315 @@
+package simulator8086
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+)
+
+const inputLogHeader = "sim8086 input log 1"
+
+// an input line holds up to 64 KB of data in hex after the name, instruction and address
+const maxInputLogLineSize = 2*0x10000 + 64
+
+type InputEventType int
+
+const (
+	IE_PortRead InputEventType = iota
+	IE_Interrupt
+	// hash of the registers, recorded every CheckpointInterval instructions to find divergences early
+	IE_Checkpoint
+	// bytes read from the host by a Go interrupt handler, like the console input of DOS
+	IE_Input
+)
+
+var inputEventNames = []string{"port", "int", "check", "input"}
+
+// InputEvent is a value that entered the simulation from outside.
+// Port reads and input happen during instruction number Instruction, counted from 0, interrupts and
+// checkpoints after Instruction instructions have completed.
+type InputEvent struct {
+	Type        InputEventType
+	Instruction uint64
+	// physical address of the instruction that is executed next, or during port reads the current one
+	Address int
+	Port    uint16
+	// the value read from the port, the interrupt vector or the register hash
+	Value uint64
+	// the bytes of an input, empty at the end of the input
+	Data string
+}
+
+func (e InputEvent) String() string {
+	switch e.Type {
+	case IE_PortRead:
+		return fmt.Sprintf("read of 0x%02x from port 0x%04x at 0x%05x", e.Value, e.Port, e.Address)
+	case IE_Interrupt:
+		return fmt.Sprintf("interrupt 0x%02x at 0x%05x", e.Value, e.Address)
+	case IE_Input:
+		return fmt.Sprintf("input of %d bytes at 0x%05x", len(e.Data), e.Address)
+	}
+	return fmt.Sprintf("registers with hash %016x at 0x%05x", e.Value, e.Address)
+}
+
+// ReplayDivergence is returned by Step when a replayed run does something the recorded run did not
+type ReplayDivergence struct {
+	Instruction uint64
+	Address     int
+	Expected    string
+	Actual      string
+}
+
+func (d *ReplayDivergence) Error() string {
+	return fmt.Sprintf("replay diverged at instruction %d (0x%05x): expected %s, got %s", d.Instruction, d.Address, d.Expected, d.Actual)
+}
+
+// InputLog records the port reads, hardware interrupts and the input read by Go interrupt handlers of a run,
+// or replays them in place of the devices and the host.
+type InputLog struct {
+	Events []InputEvent
+	// 0 disables checkpoints, 1 checks every instruction and finds the exact first divergent instruction
+	CheckpointInterval uint64
+	Replaying          bool
+	// the first divergence of the replay
+	Divergence *ReplayDivergence
+
+	position int
+}
+
+func NewInputRecording(checkpointInterval uint64) *InputLog {
+	return &InputLog{CheckpointInterval: checkpointInterval}
+}
+
+// Rewind switches to replaying the log from the start
+func (l *InputLog) Rewind() {
+	l.Replaying = true
+	l.position = 0
+	l.Divergence = nil
+}
+
+// Remaining returns the number of events that have not been replayed yet
+func (l *InputLog) Remaining() int {
+	return len(l.Events) - l.position
+}
+
+func (l *InputLog) next() (InputEvent, bool) {
+	if l.position >= len(l.Events) {
+		return InputEvent{}, false
+	}
+	return l.Events[l.position], true
+}
+
+func (l *InputLog) diverge(context *Context, address int, expected string, actual string) error {
+	if l.Divergence == nil {
+		l.Divergence = &ReplayDivergence{Instruction: context.Instructions, Address: address, Expected: expected, Actual: actual}
+	}
+	return l.Divergence
+}
+
+func (l *InputLog) expectedDescription() string {
+	event, ok := l.next()
+	if !ok {
+		return "the end of the log"
+	}
+	return fmt.Sprintf("%s after %d instructions", event, event.Instruction)
+}
+
+// portRead records the value of a device or returns the recorded one
+func (l *InputLog) portRead(context *Context, port uint16) (byte, error) {
+	address := context.InstructionAddress()
+	if !l.Replaying {
+		value := context.ReadPort(port)
+		l.Events = append(l.Events, InputEvent{Type: IE_PortRead, Instruction: context.Instructions, Address: address, Port: port, Value: uint64(value)})
+		return value, nil
+	}
+
+	event, ok := l.next()
+	if !ok || event.Type != IE_PortRead || event.Instruction != context.Instructions || event.Port != port {
+		return 0xff, l.diverge(context, address, l.expectedDescription(), fmt.Sprintf("read from port 0x%04x", port))
+	}
+	l.position++
+	return byte(event.Value), nil
+}
+
+// read records the bytes read from reader or returns the recorded ones, an empty input is io.EOF
+func (l *InputLog) read(context *Context, reader io.Reader, buffer []byte) (int, error) {
+	address := context.InstructionAddress()
+	if !l.Replaying {
+		read, err := reader.Read(buffer)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return read, err
+		}
+		l.Events = append(l.Events, InputEvent{Type: IE_Input, Instruction: context.Instructions, Address: address, Data: string(buffer[:read])})
+		if read == 0 {
+			return 0, io.EOF
+		}
+		return read, nil
+	}
+
+	event, ok := l.next()
+	if !ok || event.Type != IE_Input || event.Instruction != context.Instructions || len(event.Data) > len(buffer) {
+		return 0, l.diverge(context, address, l.expectedDescription(), fmt.Sprintf("read of up to %d bytes of input", len(buffer)))
+	}
+	l.position++
+	if len(event.Data) == 0 {
+		return 0, io.EOF
+	}
+	return copy(buffer, event.Data), nil
+}
+
+// interrupt records the vector of an acknowledged interrupt or returns the recorded one
+func (l *InputLog) interrupt(context *Context) (byte, bool, error) {
+	if !l.Replaying {
+		if context.InterruptController == nil || !context.GetFlag(Flag_Interrupt) {
+			return 0, false, nil
+		}
+		vector, ok := context.InterruptController.Acknowledge()
+		if ok {
+			l.Events = append(l.Events, InputEvent{Type: IE_Interrupt, Instruction: context.Instructions, Address: context.InstructionAddress(), Value: uint64(vector)})
+		}
+		return vector, ok, nil
+	}
+
+	event, ok := l.next()
+	if !ok || event.Type != IE_Interrupt || event.Instruction > context.Instructions {
+		return 0, false, nil
+	}
+	address := context.InstructionAddress()
+	if event.Instruction < context.Instructions || event.Address != address || !context.GetFlag(Flag_Interrupt) {
+		return 0, false, l.diverge(context, address, l.expectedDescription(), "no interrupt")
+	}
+	l.position++
+	return byte(event.Value), true, nil
+}
+
+func registerHash(context *Context) uint64 {
+	hash := fnv.New64a()
+	hash.Write(context.Registers[:])
+	binary.Write(hash, binary.LittleEndian, context.FlagsWord())
+	binary.Write(hash, binary.LittleEndian, context.InstructionPointer)
+	return hash.Sum64()
+}
+
+// checkpoint records or compares the register hash after every CheckpointInterval instructions
+func (l *InputLog) checkpoint(context *Context) error {
+	if l.CheckpointInterval == 0 || context.Instructions%l.CheckpointInterval != 0 {
+		return nil
+	}
+
+	address := context.InstructionAddress()
+	current := InputEvent{Type: IE_Checkpoint, Instruction: context.Instructions, Address: address, Value: registerHash(context)}
+	if !l.Replaying {
+		l.Events = append(l.Events, current)
+		return nil
+	}
+
+	event, ok := l.next()
+	if !ok {
+		// the recording ended earlier, there is nothing left to compare
+		return nil
+	}
+	if event.Type != IE_Checkpoint || event.Instruction != context.Instructions || event != current {
+		return l.diverge(context, address, l.expectedDescription(), current.String())
+	}
+	l.position++
+	return nil
+}
+
+func SaveInputLog(writer io.Writer, log *InputLog) error {
+	buffered := bufio.NewWriter(writer)
+	fmt.Fprintf(buffered, "%s\ncheckpoint %d\n", inputLogHeader, log.CheckpointInterval)
+	for _, event := range log.Events {
+		fmt.Fprintf(buffered, "%s %d %05x", inputEventNames[event.Type], event.Instruction, event.Address)
+		switch event.Type {
+		case IE_PortRead:
+			fmt.Fprintf(buffered, " %04x %02x\n", event.Port, event.Value)
+		case IE_Interrupt:
+			fmt.Fprintf(buffered, " %02x\n", event.Value)
+		case IE_Checkpoint:
+			fmt.Fprintf(buffered, " %016x\n", event.Value)
+		case IE_Input:
+			if len(event.Data) != 0 {
+				fmt.Fprintf(buffered, " %x", event.Data)
+			}
+			fmt.Fprintln(buffered)
+		}
+	}
+	return buffered.Flush()
+}
+
+// LoadInputLog reads a log that is ready to be replayed
+func LoadInputLog(reader io.Reader) (*InputLog, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxInputLogLineSize)
+	if !scanner.Scan() || scanner.Text() != inputLogHeader {
+		return nil, fmt.Errorf("not an input log, expected the header %q", inputLogHeader)
+	}
+
+	log := &InputLog{Replaying: true}
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("input log is missing the checkpoint interval")
+	}
+	_, err := fmt.Sscanf(scanner.Text(), "checkpoint %d", &log.CheckpointInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint interval: %w", err)
+	}
+
+	line := 2
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		event := InputEvent{Type: -1}
+		for i, name := range inputEventNames {
+			if fields[0] == name {
+				event.Type = InputEventType(i)
+			}
+		}
+		switch {
+		case event.Type == IE_PortRead && len(fields) == 5:
+			_, err = fmt.Sscanf(scanner.Text(), "port %d %x %x %x", &event.Instruction, &event.Address, &event.Port, &event.Value)
+		case event.Type == IE_Interrupt && len(fields) == 4:
+			_, err = fmt.Sscanf(scanner.Text(), "int %d %x %x", &event.Instruction, &event.Address, &event.Value)
+		case event.Type == IE_Checkpoint && len(fields) == 4:
+			_, err = fmt.Sscanf(scanner.Text(), "check %d %x %x", &event.Instruction, &event.Address, &event.Value)
+		case event.Type == IE_Input && len(fields) == 3:
+			_, err = fmt.Sscanf(scanner.Text(), "input %d %x", &event.Instruction, &event.Address)
+		case event.Type == IE_Input && len(fields) == 4:
+			_, err = fmt.Sscanf(scanner.Text(), "input %d %x %x", &event.Instruction, &event.Address, &event.Data)
+		default:
+			err = fmt.Errorf("unknown event %q", scanner.Text())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d of the input log: %w", line, err)
+		}
+		log.Events = append(log.Events, event)
+	}
+	return log, scanner.Err()
+}
+
+func SaveInputLogFile(path string, log *InputLog) error {
+	buffer := &bytes.Buffer{}
+	err := SaveInputLog(buffer, log)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+func LoadInputLogFile(path string) (*InputLog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadInputLog(file)
+}
@@ -0,0 +1,145 @@
+package simulator8086
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var timerSampleHandler = []byte{
+	0xe4, 0x40, // in al, 40h
+	0xa2, 0x02, 0x05, // mov [502h], al
+	0x83, 0x06, 0x00, 0x05, 0x01, // add word [500h], 1
+	0xb0, 0x20, // mov al, 20h
+	0xe6, 0x20, // out 20h, al
+	0xcf, // iret
+}
+
+func recordTimerRun(t *testing.T, steps int) (*Context, *InputLog) {
+	context := &Context{}
+	pic := NewPIC()
+	require.NoError(t, pic.Attach(context))
+	require.NoError(t, NewPIT(pic).Attach(context))
+	loadTimerProgram(context, timerSampleHandler)
+
+	context.InputLog = NewInputRecording(1)
+	for i := 0; i < steps; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	return context, context.InputLog
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	recorded, log := recordTimerRun(t, 5000)
+	require.NotZero(t, recorded.ReadMemory16(0x500))
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, SaveInputLog(buffer, log))
+	loaded, err := LoadInputLog(buffer)
+	require.NoError(t, err)
+	require.Equal(t, log.Events, loaded.Events)
+	require.True(t, loaded.Replaying)
+
+	// the replay has no devices, all inputs come from the log
+	replayed := &Context{}
+	loadTimerProgram(replayed, timerSampleHandler)
+	replayed.InputLog = loaded
+	for i := 0; i < 5000; i++ {
+		_, err := Step(replayed)
+		require.NoError(t, err)
+	}
+	require.Zero(t, loaded.Remaining())
+
+	replayed.Clocks = recorded.Clocks
+	require.True(t, DiffContexts(recorded, replayed).Empty())
+	require.Equal(t, recorded.InstructionPointer, replayed.InstructionPointer)
+}
+
+func TestReplayDivergence(t *testing.T) {
+	_, log := recordTimerRun(t, 2000)
+	log.Rewind()
+
+	replayed := &Context{}
+	modifiedHandler := append([]byte{}, timerSampleHandler...)
+	// a specific end of interrupt for IRQ 0 has the same effect, but leaves a different value in AL
+	modifiedHandler[11] = 0x60
+	loadTimerProgram(replayed, modifiedHandler)
+	replayed.InputLog = log
+
+	var err error
+	for i := 0; i < 2000 && err == nil; i++ {
+		_, err = Step(replayed)
+	}
+	divergence := &ReplayDivergence{}
+	require.True(t, errors.As(err, &divergence))
+	require.Equal(t, log.Divergence, divergence)
+	// the registers differ right after the first mov al in the handler
+	require.Equal(t, 0x200c, divergence.Address)
+	require.Contains(t, log.Events, InputEvent{Type: IE_Checkpoint, Instruction: divergence.Instruction, Address: 0x200c, Value: log.Events[log.position].Value})
+}
+
+func TestRecordAndReplayDOSInput(t *testing.T) {
+	program := []byte{
+		0xb4, 0x01, // mov ah, 1
+		0xcd, 0x21, // int 21h
+		0xa2, 0x00, 0x02, // mov [200h], al
+		0xb4, 0x3f, // mov ah, 3fh
+		0xbb, 0x00, 0x00, // mov bx, 0
+		0xb9, 0x04, 0x00, // mov cx, 4
+		0xba, 0x10, 0x02, // mov dx, 210h
+		0xcd, 0x21, // int 21h
+		0xa3, 0x02, 0x02, // mov [202h], ax
+		0xb4, 0x01, // mov ah, 1
+		0xcd, 0x21, // int 21h
+		0xa2, 0x04, 0x02, // mov [204h], al
+		0xb8, 0x00, 0x4c, // mov ax, 4c00h
+		0xcd, 0x21, // int 21h
+	}
+	run := func(stdin io.Reader, log *InputLog) (*Context, string) {
+		context, err := LoadCOM(program, 0x1000, "")
+		require.NoError(t, err)
+		stdout := &bytes.Buffer{}
+		(&DOS{Stdin: stdin, Stdout: stdout}).Install(context)
+		context.InputLog = log
+		runUntilHalted(t, context)
+		return context, stdout.String()
+	}
+
+	recorded, recordedOutput := run(strings.NewReader("abc"), NewInputRecording(1))
+	require.Equal(t, "a\x1a", recordedOutput)
+	require.Equal(t, []byte{'a', 0, 2, 0, 0x1a}, recorded.Memory[0x10200:0x10205])
+	require.Equal(t, []byte("bc"), recorded.Memory[0x10210:0x10212])
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, SaveInputLog(buffer, recorded.InputLog))
+	loaded, err := LoadInputLog(buffer)
+	require.NoError(t, err)
+	require.Equal(t, recorded.InputLog.Events, loaded.Events)
+
+	// the replay reads nothing from stdin, the input comes from the log
+	replayed, replayedOutput := run(nil, loaded)
+	require.Equal(t, recordedOutput, replayedOutput)
+	require.Zero(t, loaded.Remaining())
+	require.Equal(t, recorded.Memory, replayed.Memory)
+}
+
+func TestInputLogLargeInput(t *testing.T) {
+	log := NewInputRecording(1)
+	log.Events = append(log.Events, InputEvent{
+		Type:        IE_Input,
+		Instruction: 18446744073709551615,
+		Address:     0xfffff,
+		Data:        strings.Repeat("x", 0xffff),
+	})
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, SaveInputLog(buffer, log))
+	loaded, err := LoadInputLog(buffer)
+	require.NoError(t, err)
+	require.Equal(t, log.Events, loaded.Events)
+}
@@ -0,0 +1,102 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"fmt"
+)
+
+type StopReason int
+
+const (
+	SR_Halted StopReason = iota
+	SR_Limit
+	SR_Breakpoint
+	SR_Fault
+	SR_Cancelled
+)
+
+var stopReasonNames = []string{"halted", "limit", "breakpoint", "fault", "cancelled"}
+
+func (r StopReason) String() string {
+	return stopReasonNames[r]
+}
+
+// cancellation is checked every runCancelInterval steps, checking a channel every step is too slow
+const runCancelInterval = 1024
+
+type RunOptions struct {
+	// maximum number of steps, steps of a halted CPU waiting for an interrupt count too, 0 means no limit
+	MaxInstructions uint64
+	// maximum number of simulated clocks, 0 means no limit
+	MaxClocks uint64
+	// physical addresses to stop at before the instruction is executed, the first instruction
+	// of a run is executed even if there is a breakpoint at it so that a run can be continued
+	Breakpoints map[int]bool
+}
+
+type RunResult struct {
+	Reason StopReason
+	// steps and clocks of this run
+	Instructions uint64
+	Clocks       uint64
+	// physical address of the next instruction
+	Address int
+	// the error of a fault or of the cancelled Go context
+	Err error
+}
+
+func (r RunResult) String() string {
+	result := fmt.Sprintf("%s at 0x%05x after %d instructions and %d clocks", r.Reason, r.Address, r.Instructions, r.Clocks)
+	if r.Err != nil {
+		result += ": " + r.Err.Error()
+	}
+	return result
+}
+
+// haltedForever tells whether the CPU is halted and no interrupt can resume it
+func (c *Context) haltedForever() bool {
+	if !c.Halted {
+		return false
+	}
+	replaying := c.InputLog != nil && c.InputLog.Replaying
+	return !c.GetFlag(Flag_Interrupt) || (c.InterruptController == nil && !replaying)
+}
+
+// Run steps through the program until it halts for good, a limit or breakpoint is reached,
+// an instruction faults or ctx is cancelled. A halted CPU keeps waiting as long as interrupts
+// are enabled and there is an interrupt controller.
+func Run(ctx gocontext.Context, context *Context, options RunOptions) RunResult {
+	startClocks := context.Clocks
+	result := RunResult{}
+	stop := func(reason StopReason, err error) RunResult {
+		result.Reason = reason
+		result.Err = err
+		result.Clocks = context.Clocks - startClocks
+		result.Address = context.InstructionAddress()
+		return result
+	}
+
+	for {
+		if context.haltedForever() {
+			return stop(SR_Halted, nil)
+		}
+		if options.MaxInstructions != 0 && result.Instructions >= options.MaxInstructions {
+			return stop(SR_Limit, nil)
+		}
+		if options.MaxClocks != 0 && context.Clocks-startClocks >= options.MaxClocks {
+			return stop(SR_Limit, nil)
+		}
+		if result.Instructions != 0 && !context.Halted && options.Breakpoints[context.InstructionAddress()] {
+			return stop(SR_Breakpoint, nil)
+		}
+		if result.Instructions%runCancelInterval == 0 && ctx.Err() != nil {
+			return stop(SR_Cancelled, ctx.Err())
+		}
+
+		_, err := Step(context)
+		if err != nil {
+			return stop(SR_Fault, err)
+		}
+		result.Instructions++
+	}
+}
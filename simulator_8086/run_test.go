@@ -0,0 +1,76 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStopReasons(t *testing.T) {
+	background := gocontext.Background()
+
+	context := &Context{}
+	copy(context.Memory[:], []byte{
+		0xb9, 0x03, 0x00, // mov cx, 3
+		0xe2, 0xfe, // loop $
+		0xf4, // hlt
+		0x90,
+	})
+	result := Run(background, context, RunOptions{})
+	require.Equal(t, SR_Halted, result.Reason)
+	require.Equal(t, uint64(5), result.Instructions)
+	require.Equal(t, uint64(4+17+17+5+2), result.Clocks)
+	require.Equal(t, 6, result.Address)
+	require.NoError(t, result.Err)
+
+	context = &Context{}
+	loadEndlessLoopProgram(context)
+	result = Run(background, context, RunOptions{MaxInstructions: 1000})
+	require.Equal(t, SR_Limit, result.Reason)
+	require.Equal(t, uint64(1000), context.Instructions)
+
+	result = Run(background, context, RunOptions{MaxClocks: 100})
+	require.Equal(t, SR_Limit, result.Reason)
+	require.GreaterOrEqual(t, result.Clocks, uint64(100))
+	require.Less(t, result.Clocks, uint64(100+17))
+
+	result = Run(background, context, RunOptions{Breakpoints: map[int]bool{6: true}})
+	require.Equal(t, SR_Breakpoint, result.Reason)
+	require.Equal(t, 6, result.Address)
+	// continuing from the breakpoint executes the instruction at it
+	result = Run(background, context, RunOptions{MaxInstructions: 1, Breakpoints: map[int]bool{6: true}})
+	require.Equal(t, SR_Limit, result.Reason)
+
+	cancelled, cancel := gocontext.WithCancel(background)
+	cancel()
+	result = Run(cancelled, context, RunOptions{})
+	require.Equal(t, SR_Cancelled, result.Reason)
+	require.ErrorIs(t, result.Err, gocontext.Canceled)
+
+	context = &Context{}
+	copy(context.Memory[:], []byte{0x90, 0xcc})
+	result = Run(background, context, RunOptions{})
+	require.Equal(t, SR_Fault, result.Reason)
+	require.Error(t, result.Err)
+	require.Equal(t, 0, result.Address)
+}
+
+func TestRunWaitsForInterruptWhileHalted(t *testing.T) {
+	context := &Context{}
+	pic := NewPIC()
+	require.NoError(t, pic.Attach(context))
+	require.NoError(t, NewPIT(pic).Attach(context))
+	loadTimerProgram(context, []byte{
+		0xb0, 0x20, // mov al, 20h
+		0xe6, 0x20, // out 20h, al
+		0xcf, // iret
+	})
+	// replace the loop at the end with hlt, cli, hlt
+	copy(context.Memory[0x101d:], []byte{0xf4, 0xfa, 0xf4})
+
+	result := Run(gocontext.Background(), context, RunOptions{MaxInstructions: 100000})
+	require.Equal(t, SR_Halted, result.Reason)
+	require.Equal(t, 0x1020, result.Address)
+	require.GreaterOrEqual(t, result.Clocks, uint64(400))
+}
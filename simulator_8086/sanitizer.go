@@ -0,0 +1,292 @@
+package simulator8086
+
+import "fmt"
+
+type SanitizerViolationType int
+
+const (
+	SV_UndefinedMemoryRead SanitizerViolationType = iota
+	SV_UndefinedRegisterRead
+	// RET to an address that was not pushed by a CALL
+	SV_ForeignReturn
+	SV_StackOutOfRange
+	SV_CodeWrite
+)
+
+type SanitizerViolation struct {
+	Type SanitizerViolationType
+	// physical address of the instruction
+	Address int
+	// the memory address that was read or written, the stack address of a return or SS:SP
+	Target   int
+	Register RegisterName
+}
+
+func (v *SanitizerViolation) Error() string {
+	switch v.Type {
+	case SV_UndefinedMemoryRead:
+		return fmt.Sprintf("read of undefined memory at 0x%05x by the instruction at 0x%05x", v.Target, v.Address)
+	case SV_UndefinedRegisterRead:
+		return fmt.Sprintf("read of undefined register %s by the instruction at 0x%05x", v.Register, v.Address)
+	case SV_ForeignReturn:
+		return fmt.Sprintf("return at 0x%05x to an address at 0x%05x that was not pushed by a call", v.Address, v.Target)
+	case SV_StackOutOfRange:
+		return fmt.Sprintf("stack pointer 0x%05x outside of the stack after the instruction at 0x%05x", v.Target, v.Address)
+	}
+	return fmt.Sprintf("write into the code at 0x%05x by the instruction at 0x%05x", v.Target, v.Address)
+}
+
+type SanitizerOptions struct {
+	// physical addresses of the program, writes into it are violations, it is defined from the start
+	CodeStart int
+	CodeSize  int
+	// physical addresses SS:SP has to stay within, StackTop itself is the empty stack, a StackTop of 0 disables the check
+	StackBottom int
+	StackTop    int
+	// stop the simulation at the first violation, the instruction is not executed if possible
+	FaultOnViolation bool
+}
+
+// Sanitizer tracks whether every byte of memory and of the registers was written and reports reads of
+// undefined values. Like memcheck of valgrind, moves, pushes, pops and exchanges copy the definedness
+// instead of reporting it, so saving registers that were never set is fine. Every violation is
+// reported once per instruction address. The flags are not tracked.
+type Sanitizer struct {
+	BaseObserver
+	Options    SanitizerOptions
+	Violations []SanitizerViolation
+
+	memory    []bool
+	registers [24]bool
+	// stack addresses of the return addresses pushed by calls
+	returnAddresses map[int]bool
+	reported        map[SanitizerViolation]bool
+
+	address       int
+	inInstruction bool
+	// set while executing a copy, the definedness of the written values
+	copying     bool
+	copyDefined bool
+}
+
+// NewSanitizer starts with undefined memory except for the code and undefined registers
+// except for SP and the segment registers
+func NewSanitizer(options SanitizerOptions) *Sanitizer {
+	s := &Sanitizer{
+		Options:         options,
+		memory:          make([]bool, MemorySize),
+		returnAddresses: map[int]bool{},
+		reported:        map[SanitizerViolation]bool{},
+	}
+	s.DefineMemory(options.CodeStart, options.CodeSize)
+	s.DefineRegisters(SP, CS, DS, ES, SS)
+	return s
+}
+
+func (s *Sanitizer) DefineMemory(start int, size int) {
+	for i := 0; i < size; i++ {
+		s.memory[(start+i)%MemorySize] = true
+	}
+}
+
+func (s *Sanitizer) DefineRegisters(names ...RegisterName) {
+	for _, name := range names {
+		s.setRegisterDefined(name, true)
+	}
+}
+
+// AllowReturn accepts a return address at the stack address that was not pushed by a call,
+// like the 0 DOS pushes before starting a .com program
+func (s *Sanitizer) AllowReturn(stackAddress int) {
+	s.returnAddresses[stackAddress] = true
+}
+
+func (s *Sanitizer) setRegisterDefined(name RegisterName, defined bool) {
+	position, wide := getPositionAndWide(name)
+	s.registers[position] = defined
+	if wide {
+		s.registers[position+1] = defined
+	}
+}
+
+func (s *Sanitizer) registerDefined(name RegisterName) bool {
+	position, wide := getPositionAndWide(name)
+	return s.registers[position] && (!wide || s.registers[position+1])
+}
+
+func (s *Sanitizer) report(context *Context, violation SanitizerViolation) {
+	if s.reported[violation] {
+		return
+	}
+	s.reported[violation] = true
+	s.Violations = append(s.Violations, violation)
+	if s.Options.FaultOnViolation && s.inInstruction && context.fault == nil {
+		context.fault = &violation
+	}
+}
+
+func (s *Sanitizer) checkRegister(context *Context, name RegisterName) {
+	if !s.registerDefined(name) {
+		s.report(context, SanitizerViolation{Type: SV_UndefinedRegisterRead, Address: s.address, Target: -1, Register: name})
+	}
+}
+
+func addressRegisters(calculation AddressCalculation) []RegisterName {
+	switch calculation.Type {
+	case ACT_BX_SI, ACT_BX_SI_D8, ACT_BX_SI_D16:
+		return []RegisterName{BX, SI}
+	case ACT_BX_DI, ACT_BX_DI_D8, ACT_BX_DI_D16:
+		return []RegisterName{BX, DI}
+	case ACT_BP_SI, ACT_BP_SI_D8, ACT_BP_SI_D16:
+		return []RegisterName{BP, SI}
+	case ACT_BP_DI, ACT_BP_DI_D8, ACT_BP_DI_D16:
+		return []RegisterName{BP, DI}
+	case ACT_SI, ACT_SI_D8, ACT_SI_D16:
+		return []RegisterName{SI}
+	case ACT_DI, ACT_DI_D8, ACT_DI_D16:
+		return []RegisterName{DI}
+	case ACT_BP_D8, ACT_BP_D16:
+		return []RegisterName{BP}
+	case ACT_BX, ACT_BX_D8, ACT_BX_D16:
+		return []RegisterName{BX}
+	}
+	return nil
+}
+
+func (s *Sanitizer) memoryDefined(address int, wide bool) bool {
+	return s.memory[address%MemorySize] && (!wide || s.memory[(address+1)%MemorySize])
+}
+
+// operandDefined tells whether the value of an operand is defined, the registers of the address are checked separately
+func (s *Sanitizer) operandDefined(context *Context, location DataLocation) bool {
+	switch location.Type {
+	case DL_Register:
+		return s.registerDefined(location.RegisterName)
+	case DL_Memory:
+		return s.memoryDefined(context.EffectiveAddress(location.AddressCalculation), location.Wide)
+	}
+	return true
+}
+
+func isPush(t InstructionType) bool {
+	return t == IT_PushRegMem || t == IT_PushReg || t == IT_PushSegReg
+}
+
+func isPop(t InstructionType) bool {
+	return t == IT_PopRegMem || t == IT_PopReg || t == IT_PopSegReg
+}
+
+func isExchange(t InstructionType) bool {
+	return t == IT_ExchangeRegMemWithReg || t == IT_ExchangeRegWithAcc
+}
+
+func isCopy(t InstructionType) bool {
+	switch t {
+	case IT_MovRegMemToFromReg, IT_MovImToRegMem, IT_MovImToReg, IT_MovMemToAcc, IT_MovAccToMem,
+		IT_MovRegMemToSegReg, IT_MovSegRegToRegMem:
+		return true
+	}
+	return isPush(t) || isPop(t) || isExchange(t)
+}
+
+// writesOnly tells whether the destination is written without being read
+func writesOnly(t InstructionType) bool {
+	return isCopy(t) || t == IT_LoadEA || t == IT_LoadDS || t == IT_LoadES || t == IT_InFixed || t == IT_InVariable
+}
+
+func (s *Sanitizer) BeforeInstruction(context *Context, address int, instruction Instruction) {
+	s.address = address
+	s.inInstruction = true
+
+	for _, location := range [2]DataLocation{instruction.Destination, instruction.Source} {
+		if location.Type == DL_Memory {
+			for _, name := range addressRegisters(location.AddressCalculation) {
+				s.checkRegister(context, name)
+			}
+		}
+	}
+
+	s.copying = isCopy(instruction.Type)
+	switch {
+	case isPush(instruction.Type):
+		s.copyDefined = s.operandDefined(context, instruction.Destination)
+	case isPop(instruction.Type):
+		s.copyDefined = s.memoryDefined(PhysicalAddress(context.GetRegister(SS), context.GetRegister(SP)), true)
+	case isExchange(instruction.Type):
+		s.copyDefined = s.operandDefined(context, instruction.Source) && s.operandDefined(context, instruction.Destination)
+	case s.copying:
+		s.copyDefined = s.operandDefined(context, instruction.Source)
+	default:
+		if instruction.Source.Type == DL_Register {
+			s.checkRegister(context, instruction.Source.RegisterName)
+		}
+		if instruction.Destination.Type == DL_Register && !writesOnly(instruction.Type) {
+			s.checkRegister(context, instruction.Destination.RegisterName)
+		}
+	}
+
+	switch instruction.Type {
+	case IT_LOOP, IT_LOOPZ, IT_LOOPNZ, IT_JCXZ:
+		s.checkRegister(context, CX)
+	case IT_InVariable, IT_OutVariable:
+		s.checkRegister(context, DX)
+	case IT_XLAT:
+		s.checkRegister(context, BX)
+		s.checkRegister(context, AL)
+	}
+
+	if isReturn(instruction.Type) {
+		stackAddress := PhysicalAddress(context.GetRegister(SS), context.GetRegister(SP))
+		if !s.returnAddresses[stackAddress] {
+			s.report(context, SanitizerViolation{Type: SV_ForeignReturn, Address: address, Target: stackAddress})
+		}
+		delete(s.returnAddresses, stackAddress)
+	}
+}
+
+func (s *Sanitizer) AfterInstruction(context *Context, address int, instruction Instruction) {
+	s.inInstruction = false
+	s.copying = false
+	if isCall(instruction.Type) && !context.InstructionFailed() {
+		s.returnAddresses[PhysicalAddress(context.GetRegister(SS), context.GetRegister(SP))] = true
+	}
+}
+
+func (s *Sanitizer) RegisterWrite(context *Context, registerName RegisterName, value int16) {
+	if registerName == SP {
+		// the stack pointer is only moved by copies, the result is always defined
+		s.setRegisterDefined(SP, true)
+		stack := PhysicalAddress(context.GetRegister(SS), value)
+		if s.Options.StackTop != 0 && (stack < s.Options.StackBottom || stack > s.Options.StackTop) {
+			s.report(context, SanitizerViolation{Type: SV_StackOutOfRange, Address: s.address, Target: stack})
+		}
+		return
+	}
+	s.setRegisterDefined(registerName, !s.copying || s.copyDefined)
+}
+
+func (s *Sanitizer) MemoryRead(context *Context, segment int16, address int, size int, value uint16) {
+	if s.copying {
+		return
+	}
+	for i := 0; i < size; i++ {
+		target := (address + i) % MemorySize
+		if !s.memory[target] {
+			s.report(context, SanitizerViolation{Type: SV_UndefinedMemoryRead, Address: s.address, Target: target})
+		}
+	}
+}
+
+func (s *Sanitizer) MemoryWrite(context *Context, segment int16, address int, size int, value uint16) {
+	for i := 0; i < size; i++ {
+		target := (address + i) % MemorySize
+		s.memory[target] = !s.copying || s.copyDefined
+		// a return address that is overwritten was not pushed by the call anymore
+		delete(s.returnAddresses, target)
+		delete(s.returnAddresses, (target+MemorySize-1)%MemorySize)
+
+		if target >= s.Options.CodeStart && target < s.Options.CodeStart+s.Options.CodeSize {
+			s.report(context, SanitizerViolation{Type: SV_CodeWrite, Address: s.address, Target: target})
+		}
+	}
+}
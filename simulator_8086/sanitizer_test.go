@@ -0,0 +1,78 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runSanitized(t *testing.T, program []byte, options SanitizerOptions) (*Sanitizer, RunResult) {
+	context := &Context{}
+	copy(context.Memory[0x100:], program)
+	context.InstructionPointer = 0x100
+	context.SetRegister(SP, 0x1000)
+
+	options.CodeStart = 0x100
+	options.CodeSize = len(program)
+	sanitizer := NewSanitizer(options)
+	context.AddObserver(sanitizer)
+	return sanitizer, Run(gocontext.Background(), context, RunOptions{MaxInstructions: 100})
+}
+
+func TestSanitizerUndefinedReads(t *testing.T) {
+	sanitizer, _ := runSanitized(t, []byte{
+		0x53,                   // push bx
+		0x5b,                   // pop bx
+		0x8b, 0x0e, 0x00, 0x20, // mov cx, [0x2000]
+		0x89, 0x0e, 0x02, 0x20, // mov [0x2002], cx
+		0x01, 0xd8, // add ax, bx
+		0x8b, 0x16, 0x02, 0x20, // mov dx, [0x2002]
+		0x01, 0xd2, // add dx, dx
+		0xb6, 0x01, // mov dh, 1
+		0xa1, 0x00, 0x30, // mov ax, [0x3000]
+		0xa1, 0x00, 0x30, // mov ax, [0x3000]
+		0x03, 0x06, 0x04, 0x20, // add ax, [0x2004]
+		0xf4, // hlt
+	}, SanitizerOptions{})
+
+	require.Equal(t, []SanitizerViolation{
+		{Type: SV_UndefinedRegisterRead, Address: 0x10a, Target: -1, Register: BX},
+		{Type: SV_UndefinedRegisterRead, Address: 0x10a, Target: -1, Register: AX},
+		{Type: SV_UndefinedRegisterRead, Address: 0x110, Target: -1, Register: DX},
+		{Type: SV_UndefinedRegisterRead, Address: 0x11a, Target: -1, Register: AX},
+		{Type: SV_UndefinedMemoryRead, Address: 0x11a, Target: 0x2004},
+		{Type: SV_UndefinedMemoryRead, Address: 0x11a, Target: 0x2005},
+	}, sanitizer.Violations)
+	require.Equal(t, "read of undefined register bx by the instruction at 0x0010a", sanitizer.Violations[0].Error())
+}
+
+func TestSanitizerStack(t *testing.T) {
+	sanitizer, _ := runSanitized(t, []byte{
+		0xe8, 0x06, 0x00, // call f
+		0xb8, 0x08, 0x01, // mov ax, 0x108
+		0x50, // push ax
+		0xc3, // ret
+		0xc3, // ret
+		0xc3, // f: ret
+	}, SanitizerOptions{StackBottom: 0xff0, StackTop: 0x1000})
+	require.Equal(t, []SanitizerViolation{
+		{Type: SV_ForeignReturn, Address: 0x107, Target: 0xffe},
+		{Type: SV_ForeignReturn, Address: 0x108, Target: 0x1000},
+		{Type: SV_UndefinedMemoryRead, Address: 0x108, Target: 0x1000},
+		{Type: SV_UndefinedMemoryRead, Address: 0x108, Target: 0x1001},
+		{Type: SV_StackOutOfRange, Address: 0x108, Target: 0x1002},
+	}, sanitizer.Violations[:5])
+}
+
+func TestSanitizerFaultOnCodeWrite(t *testing.T) {
+	sanitizer, result := runSanitized(t, []byte{
+		0xc6, 0x06, 0x06, 0x01, 0x90, // mov byte [0x106], 0x90
+		0xf4, // hlt
+		0xf4, // hlt
+	}, SanitizerOptions{FaultOnViolation: true})
+	require.Equal(t, SR_Fault, result.Reason)
+	require.Equal(t, 0x100, result.Address)
+	require.Equal(t, &SanitizerViolation{Type: SV_CodeWrite, Address: 0x100, Target: 0x106}, result.Err)
+	require.Len(t, sanitizer.Violations, 1)
+}
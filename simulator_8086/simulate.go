@@ -11,8 +11,24 @@ const (
 	Flag_AuxilliaryCarry
 	Flag_Parity
 	Flag_Overflow
+	Flag_Trap
+	Flag_Interrupt
+	Flag_Direction
 )
 
+// bit positions of the flags in the 16-bit flags register
+var flagBits = []uint16{
+	Flag_Zero:            6,
+	Flag_Sign:            7,
+	Flag_Carry:           0,
+	Flag_AuxilliaryCarry: 4,
+	Flag_Parity:          2,
+	Flag_Overflow:        11,
+	Flag_Trap:            8,
+	Flag_Interrupt:       9,
+	Flag_Direction:       10,
+}
+
 func (f FlagIndex) Name() string {
 	switch f {
 	case Flag_Zero:
@@ -27,16 +43,58 @@ func (f FlagIndex) Name() string {
 		return "Flag_Parity"
 	case Flag_Overflow:
 		return "Flag_Overflow"
+	case Flag_Trap:
+		return "Flag_Trap"
+	case Flag_Interrupt:
+		return "Flag_Interrupt"
+	case Flag_Direction:
+		return "Flag_Direction"
 	}
 
 	return "invalid FlagIndex"
 }
 
+const MemorySize = 1024 * 1024
+
+type InterruptHandler func(context *Context, number byte) error
+
 type Context struct {
 	Registers          [24]byte
-	Flags              [6]bool
+	Flags              [9]bool
 	InstructionPointer int16
-	Memory             [1024 * 1024]byte
+	Memory             [MemorySize]byte
+
+	History   *History
+	Observer  Observer
+	MemoryMap *MemoryMap
+
+	// handlers implemented in Go take precedence over the interrupt vector table
+	InterruptHandlers [256]InterruptHandler
+	Halted            bool
+
+	Ports *PortMap
+	// asked for pending hardware interrupts between instructions while IF is set
+	InterruptController InterruptController
+	ClockedDevices      []ClockedDevice
+	// estimated number of clocks simulated by Step
+	Clocks uint64
+	// number of instructions completed by Step
+	Instructions uint64
+	// records or replays port reads and hardware interrupts
+	InputLog *InputLog
+	// decoded instructions by physical address, Step decodes every fetch if nil
+	InstructionCache *InstructionCache
+
+	// set by a faulting memory access during the current instruction
+	fault error
+	// old values of the memory written by the current instruction, to undo it when it faults
+	journal    []historyEntry
+	journaling bool
+	failed     bool
+}
+
+func PhysicalAddress(segment int16, offset int16) int {
+	return (int(uint16(segment))<<4 + int(uint16(offset))) % MemorySize
 }
 
 func getPositionAndWide(registerName RegisterName) (int, bool) {
@@ -111,6 +169,12 @@ func getPositionAndWide(registerName RegisterName) (int, bool) {
 
 func (c *Context) SetRegister(registerName RegisterName, value int16) {
 	position, wide := getPositionAndWide(registerName)
+	if c.History != nil {
+		c.History.recordRegister(c, position, wide)
+	}
+	if c.Observer != nil {
+		c.Observer.RegisterWrite(c, registerName, value)
+	}
 
 	if wide {
 		c.Registers[position] = byte(value >> 8)
@@ -145,6 +209,161 @@ func (c *Context) ResetFlags() {
 	}
 }
 
+func (c *Context) FlagsWord() uint16 {
+	// the upper four bits and bit 1 always read as set on the 8086
+	value := uint16(0xf002)
+	for i, set := range c.Flags {
+		if set {
+			value |= 1 << flagBits[i]
+		}
+	}
+	return value
+}
+
+func (c *Context) SetFlagsWord(value uint16) {
+	for i := range c.Flags {
+		c.Flags[i] = value&(1<<flagBits[i]) != 0
+	}
+}
+
+func (c *Context) InstructionAddress() int {
+	return PhysicalAddress(c.GetRegister(CS), c.InstructionPointer)
+}
+
+func (c *Context) ReadMemory8(address int) byte {
+	address %= MemorySize
+	if c.MemoryMap != nil {
+		return c.MemoryMap.read(c, address)
+	}
+	return c.Memory[address]
+}
+
+func (c *Context) ReadMemory16(address int) uint16 {
+	return uint16(c.ReadMemory8(address)) | uint16(c.ReadMemory8(address+1))<<8
+}
+
+func (c *Context) WriteMemory8(address int, value byte) {
+	address %= MemorySize
+	if c.MemoryMap != nil && !c.MemoryMap.write(c, address, value) {
+		return
+	}
+	if c.History != nil {
+		c.History.recordMemory(c, address)
+	}
+	if c.journaling {
+		c.journal = append(c.journal, historyEntry{Type: historyEntryTypeMemory, OldValue: c.Memory[address], Address: int32(address)})
+	}
+	c.Memory[address] = value
+	if c.InstructionCache != nil {
+		c.InstructionCache.invalidate(address)
+	}
+}
+
+func (c *Context) WriteMemory16(address int, value uint16) {
+	c.WriteMemory8(address, byte(value))
+	c.WriteMemory8(address+1, byte(value>>8))
+}
+
+// readData and writeData are the memory accesses of the simulated program, in contrast to
+// ReadMemory* and WriteMemory* they are reported to the observer, along with the segment of the address
+func (c *Context) readData(segment int16, address int, wide bool) uint16 {
+	size := 1
+	value := uint16(0)
+	if wide {
+		size = 2
+		value = c.ReadMemory16(address)
+	} else {
+		value = uint16(c.ReadMemory8(address))
+	}
+	if c.Observer != nil {
+		c.Observer.MemoryRead(c, segment, address%MemorySize, size, value)
+	}
+	return value
+}
+
+func (c *Context) writeData(segment int16, address int, value uint16, wide bool) {
+	size := 1
+	if wide {
+		size = 2
+		c.WriteMemory16(address, value)
+	} else {
+		c.WriteMemory8(address, byte(value))
+	}
+	if c.Observer != nil {
+		c.Observer.MemoryWrite(c, segment, address%MemorySize, size, value)
+	}
+}
+
+func (c *Context) push(value int16) {
+	sp := c.GetRegister(SP) - 2
+	c.SetRegister(SP, sp)
+	ss := c.GetRegister(SS)
+	c.writeData(ss, PhysicalAddress(ss, sp), uint16(value), true)
+}
+
+func (c *Context) pop() int16 {
+	sp := c.GetRegister(SP)
+	ss := c.GetRegister(SS)
+	value := int16(c.readData(ss, PhysicalAddress(ss, sp), true))
+	c.SetRegister(SP, sp+2)
+	return value
+}
+
+func (c *Context) Interrupt(number byte) error {
+	if c.Observer != nil {
+		c.Observer.Interrupt(c, number)
+	}
+
+	handler := c.InterruptHandlers[number]
+	if handler != nil {
+		return handler(c, number)
+	}
+
+	c.push(int16(c.FlagsWord()))
+	c.SetFlag(Flag_Interrupt, false)
+	c.SetFlag(Flag_Trap, false)
+	c.push(c.GetRegister(CS))
+	c.push(c.InstructionPointer)
+
+	vector := int(number) * 4
+	c.InstructionPointer = int16(c.readData(0, vector, true))
+	c.SetRegister(CS, int16(c.readData(0, vector+2, true)))
+	return nil
+}
+
+func (c *Context) EffectiveAddress(calculation AddressCalculation) int {
+	_, address := c.segmentAndAddress(calculation)
+	return address
+}
+
+// segmentAndAddress returns the value of the segment register a memory operand uses and its physical address
+func (c *Context) segmentAndAddress(calculation AddressCalculation) (int16, int) {
+	segment := DS
+	offset := int16(0)
+	switch calculation.Type {
+	case ACT_BX_SI, ACT_BX_SI_D8, ACT_BX_SI_D16:
+		offset = c.GetRegister(BX) + c.GetRegister(SI)
+	case ACT_BX_DI, ACT_BX_DI_D8, ACT_BX_DI_D16:
+		offset = c.GetRegister(BX) + c.GetRegister(DI)
+	case ACT_BP_SI, ACT_BP_SI_D8, ACT_BP_SI_D16:
+		segment = SS
+		offset = c.GetRegister(BP) + c.GetRegister(SI)
+	case ACT_BP_DI, ACT_BP_DI_D8, ACT_BP_DI_D16:
+		segment = SS
+		offset = c.GetRegister(BP) + c.GetRegister(DI)
+	case ACT_SI, ACT_SI_D8, ACT_SI_D16:
+		offset = c.GetRegister(SI)
+	case ACT_DI, ACT_DI_D8, ACT_DI_D16:
+		offset = c.GetRegister(DI)
+	case ACT_BP_D8, ACT_BP_D16:
+		segment = SS
+		offset = c.GetRegister(BP)
+	case ACT_BX, ACT_BX_D8, ACT_BX_D16:
+		offset = c.GetRegister(BX)
+	}
+	return c.GetRegister(segment), PhysicalAddress(c.GetRegister(segment), offset+calculation.Displacement)
+}
+
 func (c *Context) GetValue(location *DataLocation) int16 {
 	switch location.Type {
 	case DL_Invalid:
@@ -156,7 +375,8 @@ func (c *Context) GetValue(location *DataLocation) int16 {
 	case DL_Register:
 		return c.GetRegister(location.RegisterName)
 	case DL_Memory:
-		panic("TODO")
+		segment, address := c.segmentAndAddress(location.AddressCalculation)
+		return int16(c.readData(segment, address, location.Wide))
 	}
 	return 0
 }
@@ -172,7 +392,8 @@ func (c *Context) SetValue(destination *DataLocation, value int16, updateFlags b
 	case DL_Register:
 		c.SetRegister(destination.RegisterName, value)
 	case DL_Memory:
-		panic("TODO")
+		segment, address := c.segmentAndAddress(destination.AddressCalculation)
+		c.writeData(segment, address, uint16(value), destination.Wide)
 	}
 
 	if !updateFlags {
@@ -190,54 +411,364 @@ func (c *Context) SetValue(destination *DataLocation, value int16, updateFlags b
 	if parity%2 == 0 {
 		c.SetFlag(Flag_Parity, true)
 	}
-	fmt.Printf("%d %8b %d\n", value, value, parity)
 
 	if value < 0 {
 		c.SetFlag(Flag_Sign, true)
 	}
 }
 
+func (c *Context) isJumpTaken(instructionType InstructionType) bool {
+	zero := c.GetFlag(Flag_Zero)
+	sign := c.GetFlag(Flag_Sign)
+	carry := c.GetFlag(Flag_Carry)
+	overflow := c.GetFlag(Flag_Overflow)
+	parity := c.GetFlag(Flag_Parity)
+
+	switch instructionType {
+	case IT_JE:
+		return zero
+	case IT_JNE:
+		return !zero
+	case IT_JL:
+		return sign != overflow
+	case IT_JLE:
+		return zero || sign != overflow
+	case IT_JB:
+		return carry
+	case IT_JBE:
+		return carry || zero
+	case IT_JP:
+		return parity
+	case IT_JO:
+		return overflow
+	case IT_JS:
+		return sign
+	case IT_JNL:
+		return sign == overflow
+	case IT_JNLE:
+		return !zero && sign == overflow
+	case IT_JNB:
+		return !carry
+	case IT_JNBE:
+		return !carry && !zero
+	case IT_JNP:
+		return !parity
+	case IT_JNO:
+		return !overflow
+	case IT_JNS:
+		return !sign
+	case IT_LOOP:
+		c.SetRegister(CX, c.GetRegister(CX)-1)
+		return c.GetRegister(CX) != 0
+	case IT_LOOPZ:
+		c.SetRegister(CX, c.GetRegister(CX)-1)
+		return c.GetRegister(CX) != 0 && zero
+	case IT_LOOPNZ:
+		c.SetRegister(CX, c.GetRegister(CX)-1)
+		return c.GetRegister(CX) != 0 && !zero
+	case IT_JCXZ:
+		return c.GetRegister(CX) == 0
+	}
+
+	return false
+}
+
+// SimulateInstruction executes an instruction at CS:IP. When it fails, whether by a faulting memory access,
+// an observer or an interrupt handler returning an error, or because it is not implemented, the registers,
+// flags and memory it wrote are restored, IP is left at it and the error is returned.
+// Observers are told about the end of every instruction, InstructionFailed tells whether it was undone.
 func SimulateInstruction(context *Context, instruction Instruction) error {
+	address := 0
+	context.fault = nil
+	registers, flags, instructionPointer := context.Registers, context.Flags, context.InstructionPointer
+	var err error
+	if context.Observer != nil {
+		address = context.InstructionAddress()
+		context.Observer.BeforeInstruction(context, address, instruction)
+		// an observer can reject the instruction before it is executed
+		err = context.fault
+	}
+
+	if err == nil {
+		context.journal = context.journal[:0]
+		context.journaling = true
+		err = simulateInstruction(context, instruction)
+		context.journaling = false
+		if err == nil {
+			err = context.fault
+		}
+	}
+	context.fault = nil
+	if err != nil {
+		// the failed instruction is undone, so that it can be executed again
+		context.undoInstruction(registers, flags, instructionPointer)
+	}
+
+	if context.Observer != nil {
+		context.failed = err != nil
+		context.Observer.AfterInstruction(context, address, instruction)
+		context.failed = false
+	}
+	return err
+}
+
+// InstructionFailed tells observers whether the instruction that AfterInstruction is called for was undone
+func (c *Context) InstructionFailed() bool {
+	return c.failed
+}
+
+// undoInstruction restores the registers and the memory written by the current instruction,
+// writes to devices can not be undone
+func (c *Context) undoInstruction(registers [24]byte, flags [9]bool, instructionPointer int16) {
+	for i := len(c.journal) - 1; i >= 0; i-- {
+		entry := c.journal[i]
+		c.Memory[entry.Address] = entry.OldValue
+		if c.InstructionCache != nil {
+			c.InstructionCache.invalidate(int(entry.Address))
+		}
+	}
+	c.journal = c.journal[:0]
+	c.Registers = registers
+	c.Flags = flags
+	c.InstructionPointer = instructionPointer
+}
+
+func simulateInstruction(context *Context, instruction Instruction) error {
+	// the instruction pointer already points to the next instruction while executing the current one
+	context.InstructionPointer += int16(instruction.SizeInBytes)
+
+	if instruction.Type.IsConditionalJump() {
+		if context.isJumpTaken(instruction.Type) {
+			context.InstructionPointer += int16(instruction.Destination.LabelPosition - instruction.SizeInBytes)
+		}
+		return nil
+	}
+
 	switch instruction.Type {
 	case IT_MovImToReg:
 		context.SetRegister(instruction.Destination.RegisterName, instruction.Source.ImmediateValue)
+	case IT_MovImToRegMem:
+		fallthrough
+	case IT_MovMemToAcc:
+		fallthrough
+	case IT_MovAccToMem:
+		fallthrough
 	case IT_MovRegMemToFromReg:
 		fallthrough
 	case IT_MovSegRegToRegMem:
 		fallthrough
 	case IT_MovRegMemToSegReg:
-		value := context.GetValue(instruction.Source)
-		context.SetValue(instruction.Destination, value, false)
+		value := context.GetValue(&instruction.Source)
+		context.SetValue(&instruction.Destination, value, false)
 	case IT_SubImToRegMem:
 		fallthrough
 	case IT_SubRegMemWithRegToEither:
 		context.ResetFlags()
-		srcValue := context.GetValue(instruction.Source)
-		dstValue := context.GetValue(instruction.Destination)
+		srcValue := context.GetValue(&instruction.Source)
+		dstValue := context.GetValue(&instruction.Destination)
 		value := dstValue - srcValue
-		context.SetValue(instruction.Destination, value, true)
+		context.SetValue(&instruction.Destination, value, true)
 	case IT_AddImToRegMem:
 		fallthrough
 	case IT_AddRegMemWithRegToEither:
-		srcValue := context.GetValue(instruction.Source)
-		dstValue := context.GetValue(instruction.Destination)
+		srcValue := context.GetValue(&instruction.Source)
+		dstValue := context.GetValue(&instruction.Destination)
 		value := srcValue + dstValue
-		context.SetValue(instruction.Destination, value, true)
+		context.SetValue(&instruction.Destination, value, true)
 	case IT_CmpRegMemAndReg:
-		// srcValue := context.GetValue(instruction.Source)
-		// dstValue := context.GetValue(instruction.Destination)
+		// srcValue := context.GetValue(&instruction.Source)
+		// dstValue := context.GetValue(&instruction.Destination)
+	case IT_InterruptTypeSpecified:
+		return context.Interrupt(byte(instruction.Destination.ImmediateValue))
+	case IT_InterruptType3:
+		return context.Interrupt(3)
+	case IT_InterruptOnOverflow:
+		if context.GetFlag(Flag_Overflow) {
+			return context.Interrupt(4)
+		}
+	case IT_PushReg:
+		fallthrough
+	case IT_PushSegReg:
+		fallthrough
+	case IT_PushRegMem:
+		context.push(context.GetValue(&instruction.Destination))
+	case IT_PopReg:
+		fallthrough
+	case IT_PopSegReg:
+		fallthrough
+	case IT_PopRegMem:
+		context.SetValue(&instruction.Destination, context.pop(), false)
+	case IT_PushFlags:
+		context.push(int16(context.FlagsWord()))
+	case IT_PopFlags:
+		context.SetFlagsWord(uint16(context.pop()))
+	case IT_CallDirectWithinSegment:
+		context.push(context.InstructionPointer)
+		context.InstructionPointer += int16(instruction.Destination.LabelPosition - instruction.SizeInBytes)
+	case IT_JumpDirectWithinSegment:
+		fallthrough
+	case IT_JumpDirectWithinSegmentShort:
+		context.InstructionPointer += int16(instruction.Destination.LabelPosition - instruction.SizeInBytes)
+	case IT_CallIndirectWithinSegment:
+		target := context.GetValue(&instruction.Destination)
+		context.push(context.InstructionPointer)
+		context.InstructionPointer = target
+	case IT_JumpIndirectWithinSegment:
+		context.InstructionPointer = context.GetValue(&instruction.Destination)
+	case IT_CallDirectIntersegment:
+		context.push(context.GetRegister(CS))
+		context.push(context.InstructionPointer)
+		fallthrough
+	case IT_JumpDirectIntersegment:
+		context.SetRegister(CS, instruction.Destination.Segment)
+		context.InstructionPointer = instruction.Destination.ImmediateValue
+	case IT_CallIndirectIntersegment:
+		fallthrough
+	case IT_JumpIndirectIntersegment:
+		// the operand is a far pointer in memory, the offset followed by the segment
+		pointerSegment, address := context.segmentAndAddress(instruction.Destination.AddressCalculation)
+		offset := int16(context.readData(pointerSegment, address, true))
+		segment := int16(context.readData(pointerSegment, address+2, true))
+		if instruction.Type == IT_CallIndirectIntersegment {
+			context.push(context.GetRegister(CS))
+			context.push(context.InstructionPointer)
+		}
+		context.SetRegister(CS, segment)
+		context.InstructionPointer = offset
+	case IT_ReturnWithinSegment:
+		context.InstructionPointer = context.pop()
+	case IT_ReturnWithinSegmentAddingImmediateToSP:
+		context.InstructionPointer = context.pop()
+		context.SetRegister(SP, context.GetRegister(SP)+instruction.Destination.ImmediateValue)
+	case IT_ReturnIntersegment:
+		context.InstructionPointer = context.pop()
+		context.SetRegister(CS, context.pop())
+	case IT_ReturnIntersegmentAddingImmediateToSP:
+		context.InstructionPointer = context.pop()
+		context.SetRegister(CS, context.pop())
+		context.SetRegister(SP, context.GetRegister(SP)+instruction.Destination.ImmediateValue)
+	case IT_InterruptReturn:
+		context.InstructionPointer = context.pop()
+		context.SetRegister(CS, context.pop())
+		context.SetFlagsWord(uint16(context.pop()))
+	case IT_InFixed:
+		fallthrough
+	case IT_InVariable:
+		port := portNumber(context, &instruction.Source)
+		value := int16(context.readInputPort(port))
+		if instruction.Destination.RegisterName == AX {
+			value |= int16(context.readInputPort(port+1)) << 8
+		}
+		context.SetRegister(instruction.Destination.RegisterName, value)
+	case IT_OutFixed:
+		fallthrough
+	case IT_OutVariable:
+		port := portNumber(context, &instruction.Destination)
+		value := context.GetRegister(instruction.Source.RegisterName)
+		context.WritePort(port, byte(value))
+		if instruction.Source.RegisterName == AX {
+			context.WritePort(port+1, byte(value>>8))
+		}
+	case IT_ClearCarry:
+		context.SetFlag(Flag_Carry, false)
+	case IT_ComplementCarry:
+		context.SetFlag(Flag_Carry, !context.GetFlag(Flag_Carry))
+	case IT_SetCarry:
+		context.SetFlag(Flag_Carry, true)
+	case IT_ClearDirection:
+		context.SetFlag(Flag_Direction, false)
+	case IT_SetDirection:
+		context.SetFlag(Flag_Direction, true)
+	case IT_ClearInterrupt:
+		context.SetFlag(Flag_Interrupt, false)
+	case IT_SetInterrupt:
+		context.SetFlag(Flag_Interrupt, true)
+	case IT_Halt:
+		context.Halted = true
 	default:
 		return fmt.Errorf("simulation not implemented for instruction %s (%d)", instruction.Type.Name(), instruction.Type)
 	}
 	return nil
 }
 
+// portNumber returns the port of an IN or OUT instruction, either an 8-bit immediate or DX
+func portNumber(context *Context, location *DataLocation) uint16 {
+	if location.Type == DL_Immediate {
+		return uint16(byte(location.ImmediateValue))
+	}
+	return uint16(context.GetRegister(DX))
+}
+
+func decodeAt(context *Context, address int) (Instruction, error) {
+	buffer := [maxInstructionSize]byte{}
+	for i := range buffer {
+		buffer[i] = context.ReadMemory8(address + i)
+	}
+	return DecodeInstruction(buffer[:])
+}
+
+func fetchInstruction(context *Context) (Instruction, error) {
+	address := context.InstructionAddress()
+	if context.InstructionCache != nil {
+		return context.InstructionCache.fetch(context, address)
+	}
+	return decodeAt(context, address)
+}
+
+// Step simulates the next instruction, advances the clocked devices by its timing and enters
+// the handler of a pending hardware interrupt afterwards.
+// While halted no instruction is executed, only the clocks of an idle cycle pass.
+func Step(context *Context) (Instruction, error) {
+	if context.Halted {
+		if context.History != nil {
+			context.History.beginStep(context)
+		}
+		context.advanceClocks(haltedClocks)
+		return Instruction{Type: IT_Halt, SizeInBytes: 1}, context.checkInterrupts()
+	}
+
+	instruction, err := fetchInstruction(context)
+	if err != nil {
+		return instruction, err
+	}
+
+	if context.History != nil {
+		context.History.beginStep(context)
+	}
+
+	segment := context.GetRegister(CS)
+	instructionPointer := context.InstructionPointer
+	err = SimulateInstruction(context, instruction)
+	if err != nil {
+		if context.History != nil {
+			// a failed instruction is not part of the history
+			context.History.undo(context)
+		}
+		return instruction, err
+	}
+
+	branchTaken := context.GetRegister(CS) != segment || context.InstructionPointer != instructionPointer+int16(instruction.SizeInBytes)
+	context.Instructions++
+	context.advanceClocks(InstructionClocks(context, instruction, branchTaken))
+	if context.InputLog != nil {
+		err = context.InputLog.checkpoint(context)
+		if err != nil {
+			return instruction, err
+		}
+	}
+	return instruction, context.checkInterrupts()
+}
+
+// Simulate executes the instructions in order until the end of the slice, an error or HLT
 func Simulate(context *Context, instructions []Instruction) error {
 	for _, instruction := range instructions {
 		err := SimulateInstruction(context, instruction)
 		if err != nil {
 			return err
 		}
+		if context.Halted {
+			return nil
+		}
 	}
 	return nil
 }
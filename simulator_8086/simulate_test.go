@@ -10,8 +10,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func parseFlags(flagsStr string) [6]bool {
-	flags := [6]bool{}
+func parseFlags(flagsStr string) [9]bool {
+	flags := [9]bool{}
 	if strings.Contains(flagsStr, "Z") {
 		flags[Flag_Zero] = true
 	}
@@ -39,7 +39,7 @@ type ContextTransition struct {
 	NewValue          int16
 
 	HasFlagsUpdate bool
-	Flags          [6]bool
+	Flags          [9]bool
 }
 
 func createExpectedContext(inputFile string) ([]ContextTransition, error) {
@@ -105,12 +105,12 @@ func createExpectedContext(inputFile string) ([]ContextTransition, error) {
 }
 
 func requireContextsToBeEqual(t *testing.T, expected *Context, actual *Context) {
-	for i := range expected.Registers {
-		require.Equalf(t, expected.Registers[i], actual.Registers[i], "mismatch in register state at position %d", i)
-	}
-	for i := range expected.Flags {
-		require.Equalf(t, expected.Flags[i], actual.Flags[i], "mismatch in %s", FlagIndex(i).Name())
-	}
+	// the expected context does not track the instruction pointer
+	expectedWithInstructionPointer := *expected
+	expectedWithInstructionPointer.InstructionPointer = actual.InstructionPointer
+
+	diff := DiffContexts(&expectedWithInstructionPointer, actual)
+	require.Truef(t, diff.Empty(), "mismatch in context state:\n%s", diff.String())
 }
 
 func TestSimulation(t *testing.T) {
@@ -0,0 +1,308 @@
+package simulator8086
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const snapshotMagic = "SIM8086S"
+const SnapshotVersion = 1
+
+// device states are never larger than the memory, longer blobs come from a corrupt snapshot
+const maxSnapshotBlobSize = MemorySize
+
+// StatefulDevice is implemented by devices whose state is part of a snapshot
+type StatefulDevice interface {
+	DeviceName() string
+	SaveState() ([]byte, error)
+	LoadState(state []byte) error
+}
+
+type snapshotHeader struct {
+	Magic   [8]byte
+	Version uint16
+}
+
+type snapshotCPUState struct {
+	Registers          [24]byte
+	Flags              uint16
+	InstructionPointer int16
+	Halted             bool
+	Clocks             uint64
+	Instructions       uint64
+}
+
+func SaveSnapshot(writer io.Writer, context *Context, devices ...StatefulDevice) error {
+	header := snapshotHeader{Version: SnapshotVersion}
+	copy(header.Magic[:], snapshotMagic)
+	err := binary.Write(writer, binary.LittleEndian, &header)
+	if err != nil {
+		return err
+	}
+
+	compressed := gzip.NewWriter(writer)
+	cpu := snapshotCPUState{
+		Registers:          context.Registers,
+		Flags:              context.FlagsWord(),
+		InstructionPointer: context.InstructionPointer,
+		Halted:             context.Halted,
+		Clocks:             context.Clocks,
+		Instructions:       context.Instructions,
+	}
+	err = binary.Write(compressed, binary.LittleEndian, &cpu)
+	if err != nil {
+		return err
+	}
+
+	_, err = compressed.Write(context.Memory[:])
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(compressed, binary.LittleEndian, uint16(len(devices)))
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		state, err := device.SaveState()
+		if err != nil {
+			return fmt.Errorf("failed to save state of device %s: %w", device.DeviceName(), err)
+		}
+
+		err = writeSnapshotBlob(compressed, []byte(device.DeviceName()))
+		if err != nil {
+			return err
+		}
+		err = writeSnapshotBlob(compressed, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return compressed.Close()
+}
+
+func LoadSnapshot(reader io.Reader, context *Context, devices ...StatefulDevice) error {
+	header := snapshotHeader{}
+	err := binary.Read(reader, binary.LittleEndian, &header)
+	if err != nil {
+		return err
+	}
+	if string(header.Magic[:]) != snapshotMagic {
+		return errors.New("not a simulator snapshot")
+	}
+	if header.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	compressed, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+
+	cpu := snapshotCPUState{}
+	err = binary.Read(compressed, binary.LittleEndian, &cpu)
+	if err != nil {
+		return err
+	}
+
+	memory := make([]byte, MemorySize)
+	_, err = io.ReadFull(compressed, memory)
+	if err != nil {
+		return err
+	}
+
+	deviceCount := uint16(0)
+	err = binary.Read(compressed, binary.LittleEndian, &deviceCount)
+	if err != nil {
+		return err
+	}
+	states := make(map[string][]byte)
+	for i := 0; i < int(deviceCount); i++ {
+		name, err := readSnapshotBlob(compressed)
+		if err != nil {
+			return err
+		}
+		state, err := readSnapshotBlob(compressed)
+		if err != nil {
+			return err
+		}
+		states[string(name)] = state
+	}
+
+	// devices are restored first, so that a failure does not leave a partially restored context behind
+	for _, device := range devices {
+		state, found := states[device.DeviceName()]
+		if !found {
+			return fmt.Errorf("snapshot does not contain state for device %s", device.DeviceName())
+		}
+		err = device.LoadState(state)
+		if err != nil {
+			return fmt.Errorf("failed to load state of device %s: %w", device.DeviceName(), err)
+		}
+	}
+
+	context.Registers = cpu.Registers
+	context.SetFlagsWord(cpu.Flags)
+	context.InstructionPointer = cpu.InstructionPointer
+	context.Halted = cpu.Halted
+	context.Clocks = cpu.Clocks
+	context.Instructions = cpu.Instructions
+	copy(context.Memory[:], memory)
+	if context.InstructionCache != nil {
+		context.InstructionCache.Clear()
+	}
+	return nil
+}
+
+func SaveSnapshotFile(path string, context *Context, devices ...StatefulDevice) error {
+	buffer := &bytes.Buffer{}
+	err := SaveSnapshot(buffer, context, devices...)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+func LoadSnapshotFile(path string, context *Context, devices ...StatefulDevice) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return LoadSnapshot(file, context, devices...)
+}
+
+func writeSnapshotBlob(writer io.Writer, blob []byte) error {
+	err := binary.Write(writer, binary.LittleEndian, uint32(len(blob)))
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(blob)
+	return err
+}
+
+func readSnapshotBlob(reader io.Reader) ([]byte, error) {
+	length := uint32(0)
+	err := binary.Read(reader, binary.LittleEndian, &length)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxSnapshotBlobSize {
+		return nil, fmt.Errorf("snapshot blob of %d bytes is larger than the memory", length)
+	}
+	blob := make([]byte, length)
+	_, err = io.ReadFull(reader, blob)
+	return blob, err
+}
+
+type RegisterChange struct {
+	Register RegisterName
+	Old      int16
+	New      int16
+}
+
+type FlagChange struct {
+	Flag FlagIndex
+	Old  bool
+	New  bool
+}
+
+type MemoryChange struct {
+	Address int
+	Old     []byte
+	New     []byte
+}
+
+type ContextDiff struct {
+	// the instruction pointer is reported as register "ip"
+	Registers []RegisterChange
+	Flags     []FlagChange
+	Memory    []MemoryChange
+}
+
+var diffRegisterTable = []RegisterName{AX, BX, CX, DX, SP, BP, SI, DI, CS, DS, ES, SS}
+
+func DiffContexts(before *Context, after *Context) ContextDiff {
+	diff := ContextDiff{}
+	for _, register := range diffRegisterTable {
+		oldValue := before.GetRegister(register)
+		newValue := after.GetRegister(register)
+		if oldValue != newValue {
+			diff.Registers = append(diff.Registers, RegisterChange{Register: register, Old: oldValue, New: newValue})
+		}
+	}
+	if before.InstructionPointer != after.InstructionPointer {
+		diff.Registers = append(diff.Registers, RegisterChange{Register: "ip", Old: before.InstructionPointer, New: after.InstructionPointer})
+	}
+
+	for i := range before.Flags {
+		if before.Flags[i] != after.Flags[i] {
+			diff.Flags = append(diff.Flags, FlagChange{Flag: FlagIndex(i), Old: before.Flags[i], New: after.Flags[i]})
+		}
+	}
+
+	for address := 0; address < MemorySize; address++ {
+		if before.Memory[address] == after.Memory[address] {
+			continue
+		}
+
+		end := address
+		for end < MemorySize && before.Memory[end] != after.Memory[end] {
+			end++
+		}
+		diff.Memory = append(diff.Memory, MemoryChange{
+			Address: address,
+			Old:     append([]byte{}, before.Memory[address:end]...),
+			New:     append([]byte{}, after.Memory[address:end]...),
+		})
+		address = end
+	}
+
+	return diff
+}
+
+func (d ContextDiff) Empty() bool {
+	return len(d.Registers) == 0 && len(d.Flags) == 0 && len(d.Memory) == 0
+}
+
+func formatMemoryBytes(memory []byte) string {
+	const maxBytes = 16
+	result := ""
+	for i, b := range memory {
+		if i == maxBytes {
+			return result + " ..."
+		}
+		if i != 0 {
+			result += " "
+		}
+		result += fmt.Sprintf("%02x", b)
+	}
+	return result
+}
+
+func (d ContextDiff) String() string {
+	result := strings.Builder{}
+	for _, change := range d.Registers {
+		result.WriteString(fmt.Sprintf("%s: 0x%04x -> 0x%04x\n", change.Register, uint16(change.Old), uint16(change.New)))
+	}
+	for _, change := range d.Flags {
+		result.WriteString(fmt.Sprintf("%s: %t -> %t\n", change.Flag.Name(), change.Old, change.New))
+	}
+	for _, change := range d.Memory {
+		result.WriteString(fmt.Sprintf(
+			"memory 0x%05x-0x%05x: %s -> %s\n",
+			change.Address,
+			change.Address+len(change.New)-1,
+			formatMemoryBytes(change.Old),
+			formatMemoryBytes(change.New),
+		))
+	}
+	return result.String()
+}
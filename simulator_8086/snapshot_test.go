@@ -0,0 +1,96 @@
+package simulator8086
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testDevice struct {
+	state []byte
+}
+
+func (d *testDevice) DeviceName() string {
+	return "test"
+}
+
+func (d *testDevice) SaveState() ([]byte, error) {
+	return d.state, nil
+}
+
+func (d *testDevice) LoadState(state []byte) error {
+	d.state = state
+	return nil
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	context := &Context{}
+	context.SetRegister(AX, 0x1234)
+	context.SetRegister(SS, -2)
+	context.SetFlag(Flag_Carry, true)
+	context.SetFlag(Flag_Interrupt, true)
+	context.InstructionPointer = 0x100
+	context.Halted = true
+	context.Clocks = 1234
+	context.Instructions = 56
+	context.WriteMemory16(0xb8000, 0x0741)
+	device := &testDevice{state: []byte{1, 2, 3}}
+
+	buffer := &bytes.Buffer{}
+	err := SaveSnapshot(buffer, context, device)
+	require.NoError(t, err)
+
+	restored := &Context{}
+	restoredDevice := &testDevice{}
+	err = LoadSnapshot(bytes.NewReader(buffer.Bytes()), restored, restoredDevice)
+	require.NoError(t, err)
+	require.True(t, DiffContexts(context, restored).Empty())
+	require.Equal(t, device.state, restoredDevice.state)
+	require.True(t, restored.Halted)
+	require.Equal(t, uint64(1234), restored.Clocks)
+	require.Equal(t, uint64(56), restored.Instructions)
+
+	err = LoadSnapshot(bytes.NewReader([]byte("not a snapshot")), restored)
+	require.Error(t, err)
+
+	buffer.Reset()
+	err = SaveSnapshot(buffer, context)
+	require.NoError(t, err)
+	err = LoadSnapshot(buffer, restored, restoredDevice)
+	require.EqualError(t, err, "snapshot does not contain state for device test")
+}
+
+func TestReadSnapshotBlobTooLarge(t *testing.T) {
+	_, err := readSnapshotBlob(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}))
+	require.EqualError(t, err, "snapshot blob of 4294967295 bytes is larger than the memory")
+}
+
+func TestDiffContexts(t *testing.T) {
+	before := &Context{}
+	after := &Context{}
+	after.SetRegister(BX, 5)
+	after.InstructionPointer = 3
+	after.SetFlag(Flag_Zero, true)
+	after.WriteMemory16(0x10, 0xabcd)
+	after.WriteMemory8(0x20, 1)
+
+	diff := DiffContexts(before, after)
+	require.Equal(t, []RegisterChange{
+		{Register: BX, Old: 0, New: 5},
+		{Register: "ip", Old: 0, New: 3},
+	}, diff.Registers)
+	require.Equal(t, []FlagChange{{Flag: Flag_Zero, Old: false, New: true}}, diff.Flags)
+	require.Equal(t, []MemoryChange{
+		{Address: 0x10, Old: []byte{0, 0}, New: []byte{0xcd, 0xab}},
+		{Address: 0x20, Old: []byte{0}, New: []byte{1}},
+	}, diff.Memory)
+	require.Equal(t, `bx: 0x0000 -> 0x0005
+ip: 0x0000 -> 0x0003
+Flag_Zero: false -> true
+memory 0x00010-0x00011: 00 00 -> cd ab
+memory 0x00020-0x00020: 00 -> 01
+`, diff.String())
+
+	require.True(t, DiffContexts(after, after).Empty())
+}
@@ -0,0 +1,147 @@
+package simulator8086
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeInstruction(t *testing.T) {
+	tests := []struct {
+		content []byte
+		text    string
+	}{
+		{[]byte{0x89, 0xd9}, "mov cx, bx"},
+		{[]byte{0xc6, 0x03, 0x07}, "mov byte [bp + di], byte 7"},
+		{[]byte{0xa1, 0x00, 0x20}, "mov ax, word [8192]"},
+		{[]byte{0x8b, 0x46, 0x02}, "mov ax, word [bp + 2]"},
+		{[]byte{0x83, 0x2e, 0x00, 0x02, 0x01}, "sub word [512], word 1"},
+		{[]byte{0x75, 0xf9}, "jne $-5"},
+	}
+	for _, test := range tests {
+		// only the first instruction is decoded, the bytes after it are ignored
+		content := append(append([]byte{}, test.content...), 0x90, 0x90)
+		instruction, err := DecodeInstruction(content)
+		require.NoError(t, err)
+		require.Equal(t, len(test.content), instruction.SizeInBytes)
+		require.Equal(t, test.text+"\n", instruction.String())
+	}
+}
+
+func TestStepMemoryAndConditionalJumps(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[:], []byte{
+		0xc7, 0x06, 0x00, 0x02, 0x03, 0x00, // mov word [512], 3
+		0x83, 0x2e, 0x00, 0x02, 0x01, // sub word [512], 1
+		0x75, 0xf9, // jne -7
+		0x8b, 0x1e, 0x00, 0x02, // mov bx, [512]
+	})
+
+	for i := 0; i < 8; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int16(17), context.InstructionPointer)
+	require.Equal(t, int16(0), context.GetRegister(BX))
+	require.True(t, context.GetFlag(Flag_Zero))
+}
+
+func TestStepStackSegment(t *testing.T) {
+	context := &Context{}
+	context.SetRegister(SS, 0x100)
+	context.SetRegister(BP, 4)
+	context.WriteMemory16(0x1006, 0x1234)
+	copy(context.Memory[:], []byte{
+		0x8b, 0x46, 0x02, // mov ax, [bp + 2]
+	})
+
+	_, err := Step(context)
+	require.NoError(t, err)
+	require.Equal(t, int16(0x1234), context.GetRegister(AX))
+}
+
+func TestFlagsWord(t *testing.T) {
+	context := &Context{}
+	context.SetFlagsWord(0x0e41)
+	require.True(t, context.GetFlag(Flag_Carry))
+	require.True(t, context.GetFlag(Flag_Zero))
+	require.True(t, context.GetFlag(Flag_Interrupt))
+	require.True(t, context.GetFlag(Flag_Direction))
+	require.True(t, context.GetFlag(Flag_Overflow))
+	require.False(t, context.GetFlag(Flag_Sign))
+	// bit 1 and the upper four bits always read as set
+	require.Equal(t, uint16(0xfe43), context.FlagsWord())
+}
+
+func TestDecodeJumpsAndCalls(t *testing.T) {
+	tests := []struct {
+		content []byte
+		text    string
+	}{
+		{[]byte{0xe8, 0xfd, 0xff}, "call $+0"},
+		{[]byte{0xe9, 0x00, 0x01}, "jmp $+259"},
+		{[]byte{0xeb, 0xfe}, "jmp $+0"},
+		{[]byte{0xea, 0x34, 0x12, 0x00, 0xf0}, "jmp 61440:4660"},
+		{[]byte{0x9a, 0x00, 0x00, 0x20, 0x00}, "call 32:0"},
+		// the offset is sign extended before the size is added, it does not wrap at 127
+		{[]byte{0x75, 0x7f}, "jne $+129"},
+	}
+	for _, test := range tests {
+		instruction, err := DecodeInstruction(test.content)
+		require.NoError(t, err)
+		require.Equal(t, len(test.content), instruction.SizeInBytes)
+		require.Equal(t, test.text+"\n", instruction.String())
+	}
+}
+
+func TestStepPushPopCallAndReturn(t *testing.T) {
+	context := &Context{}
+	context.SetRegister(DS, 0x40)
+	copy(context.Memory[:], []byte{
+		0xbc, 0x00, 0x01, // mov sp, 0x100
+		0xb8, 0x34, 0x12, // mov ax, 0x1234
+		0x50,             // push ax
+		0xe8, 0x05, 0x00, // call +5
+		0x5b,       // pop bx
+		0x1e,       // push ds
+		0x07,       // pop es
+		0xeb, 0xfe, // jmp $
+		0x9c, // pushf
+		0x9d, // popf
+		0xc3, // ret
+	})
+
+	for i := 0; i < 11; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int16(0x1234), context.GetRegister(BX))
+	require.Equal(t, int16(0x40), context.GetRegister(ES))
+	require.Equal(t, int16(0x100), context.GetRegister(SP))
+	require.Equal(t, int16(13), context.InstructionPointer)
+}
+
+func TestStepFarCallsAndJumps(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[:], []byte{
+		0xbc, 0x00, 0x01, // mov sp, 0x100
+		0x9a, 0x00, 0x00, 0x20, 0x00, // call 0x20:0
+		0xff, 0x2e, 0x00, 0x03, // jmp far [0x300]
+	})
+	context.WriteMemory16(0x300, 0x0004)
+	context.WriteMemory16(0x302, 0x0020)
+	copy(context.Memory[0x200:], []byte{
+		0xcb,             // retf
+		0x90, 0x90, 0x90, // nop
+		0xb9, 0x07, 0x00, // mov cx, 7
+	})
+
+	for i := 0; i < 5; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int16(7), context.GetRegister(CX))
+	require.Equal(t, int16(0x20), context.GetRegister(CS))
+	require.Equal(t, int16(7), context.InstructionPointer)
+	require.Equal(t, int16(0x100), context.GetRegister(SP))
+}
@@ -0,0 +1,259 @@
+package simulator8086
+
+// clock counts are taken from the 8086 user's manual, for instructions with a data dependent
+// timing (multiplication and division) the average of the documented range is used
+
+// clocks it takes to acknowledge an external interrupt and enter its handler
+const interruptAcknowledgeClocks = 61
+
+// clocks that pass per Step while the CPU is halted
+const haltedClocks = 4
+
+// clocks of the byte and word forms with a register operand
+var multiplyDivideClocks = map[InstructionType][2]int{
+	IT_Multiply:       {74, 124},
+	IT_MultiplySigned: {89, 141},
+	IT_Divide:         {85, 153},
+	IT_DivideSigned:   {107, 174},
+}
+
+func effectiveAddressClocks(calculation AddressCalculation) int {
+	switch calculation.Type {
+	case ACT_DirectAddress:
+		return 6
+	case ACT_SI, ACT_DI, ACT_BX:
+		return 5
+	case ACT_SI_D8, ACT_DI_D8, ACT_BX_D8, ACT_BP_D8, ACT_SI_D16, ACT_DI_D16, ACT_BX_D16, ACT_BP_D16:
+		return 9
+	case ACT_BP_DI, ACT_BX_SI:
+		return 7
+	case ACT_BP_SI, ACT_BX_DI:
+		return 8
+	case ACT_BP_DI_D8, ACT_BX_SI_D8, ACT_BP_DI_D16, ACT_BX_SI_D16:
+		return 11
+	case ACT_BP_SI_D8, ACT_BX_DI_D8, ACT_BP_SI_D16, ACT_BX_DI_D16:
+		return 12
+	}
+	return 0
+}
+
+func isMemory(location DataLocation) bool {
+	return location.Type == DL_Memory
+}
+
+func isImmediate(location DataLocation) bool {
+	return location.Type == DL_Immediate
+}
+
+// memoryOperandClocks returns the effective address calculation clocks of the memory operand, if any
+func memoryOperandClocks(instruction Instruction) (int, bool) {
+	if isMemory(instruction.Destination) {
+		return effectiveAddressClocks(instruction.Destination.AddressCalculation), true
+	}
+	if isMemory(instruction.Source) {
+		return effectiveAddressClocks(instruction.Source.AddressCalculation), true
+	}
+	return 0, false
+}
+
+func isWideOperation(instruction Instruction) bool {
+	for _, location := range [2]DataLocation{instruction.Destination, instruction.Source} {
+		if location.Type == DL_Register {
+			_, wide := getPositionAndWide(location.RegisterName)
+			return wide
+		}
+		if location.Type == DL_Memory {
+			return location.Wide
+		}
+	}
+	return instruction.Wide
+}
+
+// InstructionClocks estimates the number of clocks an instruction takes on an 8086.
+// branchTaken tells whether a jump was taken, shifts and rotates by CL use the current value of CL.
+func InstructionClocks(context *Context, instruction Instruction, branchTaken bool) int {
+	ea, memory := memoryOperandClocks(instruction)
+	destinationIsMemory := isMemory(instruction.Destination)
+	immediate := isImmediate(instruction.Source)
+
+	// picks the timing of the register, memory source or memory destination form
+	form := func(register int, fromMemory int, toMemory int) int {
+		if !memory {
+			return register
+		}
+		if destinationIsMemory {
+			return toMemory + ea
+		}
+		return fromMemory + ea
+	}
+
+	switch instruction.Type {
+	case IT_MovRegMemToFromReg:
+		return form(2, 8, 9)
+	case IT_MovImToRegMem:
+		return form(4, 10, 10)
+	case IT_MovImToReg:
+		return 4
+	case IT_MovMemToAcc, IT_MovAccToMem:
+		return 10
+	case IT_MovRegMemToSegReg, IT_MovSegRegToRegMem:
+		return form(2, 8, 9)
+
+	case IT_PushRegMem:
+		return form(11, 16, 16)
+	case IT_PushReg:
+		return 11
+	case IT_PushSegReg, IT_PushFlags:
+		return 10
+	case IT_PopRegMem:
+		return form(8, 17, 17)
+	case IT_PopReg, IT_PopSegReg, IT_PopFlags:
+		return 8
+
+	case IT_ExchangeRegMemWithReg:
+		return form(4, 17, 17)
+	case IT_ExchangeRegWithAcc:
+		return 3
+
+	case IT_InFixed, IT_OutFixed:
+		return 10
+	case IT_InVariable, IT_OutVariable:
+		return 8
+
+	case IT_XLAT:
+		return 11
+	case IT_LoadEA:
+		return 2 + ea
+	case IT_LoadDS, IT_LoadES:
+		return 16 + ea
+	case IT_LoadAHWithFlags, IT_StoreAHWithFlags:
+		return 4
+
+	case IT_AddRegMemWithRegToEither, IT_AddWithCarryRegMemWithRegToEither,
+		IT_SubRegMemWithRegToEither, IT_SubWithBorrowRegMemWithRegToEither,
+		IT_AndRegMemWithRegToEither, IT_OrRegMemWithRegToEither, IT_XorRegMemWithRegToEither:
+		return form(3, 9, 16)
+	case IT_AddImToRegMem, IT_AddWithCarryImToRegMem, IT_SubImToRegMem, IT_SubWithBorrowImToRegMem,
+		IT_AndImToRegMem, IT_OrImToRegMem, IT_XorImToRegMem:
+		return form(4, 17, 17)
+	case IT_CmpRegMemAndReg:
+		return form(3, 9, 9)
+	case IT_CmpImWithRegMem:
+		return form(4, 10, 10)
+	case IT_TestRegMemAndReg:
+		return form(3, 9, 9)
+	case IT_TestImAndRegMem:
+		return form(5, 11, 11)
+	case IT_AddImToAcc, IT_AddWithCarryImToAcc, IT_SubImFromAcc, IT_SubWithBorrowImFromAcc,
+		IT_CmpImWithAcc, IT_AndImToAcc, IT_TestImAndAcc, IT_OrImToAcc, IT_XorImToAcc:
+		return 4
+
+	case IT_IncReg, IT_DecReg:
+		return 2
+	case IT_IncRegMem, IT_DecRegMem:
+		return form(3, 15, 15)
+	case IT_Neg, IT_Not:
+		return form(3, 16, 16)
+
+	case IT_AsciiAdjustForAdd, IT_DecimalAdjustForAdd, IT_AsciiAdjustForSubtract, IT_DecimalAdjustForSubtract:
+		return 4
+	case IT_AsciiAdjustForMultiply:
+		return 83
+	case IT_AsciiAdjustForDivide:
+		return 60
+	case IT_ConvertByteToWord:
+		return 2
+	case IT_ConvertWordToDoubleWord:
+		return 5
+
+	case IT_Multiply, IT_MultiplySigned, IT_Divide, IT_DivideSigned:
+		clocks := multiplyDivideClocks[instruction.Type]
+		result := clocks[0]
+		if isWideOperation(instruction) {
+			result = clocks[1]
+		}
+		if memory {
+			result += 6 + ea
+		}
+		return result
+
+	case IT_ShiftLogicLeft, IT_ShiftLogicRight, IT_ShiftArithmeticRight, IT_RotateLeft, IT_RotateRight,
+		IT_RotateThroughCarryFlagLeft, IT_RotateThroughCarryFlagRight:
+		if immediate {
+			return form(2, 15, 15)
+		}
+		return form(8, 20, 20) + 4*int(byte(context.GetRegister(CL)))
+
+	case IT_Repeat, IT_BusLockPrefix:
+		return 2
+	case IT_MoveByte:
+		return 18
+	case IT_CompareByte:
+		return 22
+	case IT_ScanByte:
+		return 15
+	case IT_LoadByte:
+		return 12
+	case IT_StoreByte:
+		return 11
+
+	case IT_CallDirectWithinSegment:
+		return 19
+	case IT_CallIndirectWithinSegment:
+		return form(16, 21, 21)
+	case IT_CallDirectIntersegment:
+		return 28
+	case IT_CallIndirectIntersegment:
+		return 37 + ea
+	case IT_JumpDirectWithinSegment, IT_JumpDirectWithinSegmentShort, IT_JumpDirectIntersegment:
+		return 15
+	case IT_JumpIndirectWithinSegment:
+		return form(11, 18, 18)
+	case IT_JumpIndirectIntersegment:
+		return 24 + ea
+	case IT_ReturnWithinSegment:
+		return 8
+	case IT_ReturnWithinSegmentAddingImmediateToSP:
+		return 12
+	case IT_ReturnIntersegment:
+		return 18
+	case IT_ReturnIntersegmentAddingImmediateToSP:
+		return 17
+
+	case IT_LOOP:
+		return branchClocks(branchTaken, 17, 5)
+	case IT_LOOPZ, IT_JCXZ:
+		return branchClocks(branchTaken, 18, 6)
+	case IT_LOOPNZ:
+		return branchClocks(branchTaken, 19, 5)
+
+	case IT_InterruptTypeSpecified:
+		return 51
+	case IT_InterruptType3:
+		return 52
+	case IT_InterruptOnOverflow:
+		return branchClocks(branchTaken, 53, 4)
+	case IT_InterruptReturn:
+		return 24
+
+	case IT_ClearCarry, IT_ComplementCarry, IT_SetCarry, IT_ClearDirection, IT_SetDirection,
+		IT_ClearInterrupt, IT_SetInterrupt, IT_Halt:
+		return 2
+	case IT_Wait:
+		return 3
+	case IT_Escape:
+		return form(2, 8, 8)
+	}
+
+	if instruction.Type.IsConditionalJump() {
+		return branchClocks(branchTaken, 16, 4)
+	}
+	return 0
+}
+
+func branchClocks(taken bool, takenClocks int, notTakenClocks int) int {
+	if taken {
+		return takenClocks
+	}
+	return notTakenClocks
+}
@@ -0,0 +1,167 @@
+package simulator8086
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TraceEvent is an event of the Chrome Trace Event Format, timestamps and durations are simulated clocks
+// that viewers show as microseconds
+type TraceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat"`
+	Phase     string            `json:"ph"`
+	Timestamp uint64            `json:"ts"`
+	Duration  uint64            `json:"dur,omitempty"`
+	ProcessID int               `json:"pid"`
+	ThreadID  int               `json:"tid"`
+	Scope     string            `json:"s,omitempty"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+type traceAction int
+
+const (
+	traceEnterCall traceAction = iota
+	traceEnterInterrupt
+	traceLeaveCall
+	traceLeaveInterrupt
+)
+
+type tracePending struct {
+	action  traceAction
+	address int
+	number  byte
+}
+
+type traceFrame struct {
+	event     TraceEvent
+	interrupt bool
+}
+
+// CallTracer turns calls and interrupts into complete events of the Chrome Trace Event Format that
+// chrome://tracing and the Perfetto UI can show as a timeline.
+// A call or interrupt starts with the first instruction of the callee and ends with the first instruction
+// after the return, so the clocks of the return belong to it. Interrupts handled in Go are instant events.
+type CallTracer struct {
+	BaseObserver
+	// names of physical addresses, other functions are named by their address
+	Symbols map[int]string
+	// the completed events
+	Events []TraceEvent
+
+	stack   []traceFrame
+	pending []tracePending
+}
+
+func NewCallTracer(symbols map[int]string) *CallTracer {
+	if symbols == nil {
+		symbols = map[int]string{}
+	}
+	return &CallTracer{Symbols: symbols}
+}
+
+func (t *CallTracer) name(address int) string {
+	name, ok := t.Symbols[address]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("sub_%05x", address)
+}
+
+func (t *CallTracer) enter(name string, category string, address int, interrupt bool, clocks uint64) {
+	t.stack = append(t.stack, traceFrame{
+		event: TraceEvent{
+			Name:      name,
+			Category:  category,
+			Phase:     "X",
+			Timestamp: clocks,
+			ProcessID: 1,
+			ThreadID:  1,
+			Args:      map[string]string{"address": fmt.Sprintf("0x%05x", address)},
+		},
+		interrupt: interrupt,
+	})
+}
+
+func (t *CallTracer) leave(interrupt bool, clocks uint64) {
+	if len(t.stack) == 0 || t.stack[len(t.stack)-1].interrupt != interrupt {
+		return
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	frame.event.Duration = clocks - frame.event.Timestamp
+	t.Events = append(t.Events, frame.event)
+}
+
+// resolve applies the calls, returns and interrupts of the last step, the clocks of the context
+// only include the instruction once the step is complete
+func (t *CallTracer) resolve(address int, clocks uint64) {
+	for _, pending := range t.pending {
+		switch pending.action {
+		case traceEnterCall:
+			t.enter(t.name(pending.address), "call", pending.address, false, clocks)
+		case traceEnterInterrupt:
+			name, ok := t.Symbols[address]
+			if !ok {
+				name = fmt.Sprintf("int 0x%02x", pending.number)
+			}
+			t.enter(name, "interrupt", address, true, clocks)
+		case traceLeaveCall:
+			t.leave(false, clocks)
+		case traceLeaveInterrupt:
+			t.leave(true, clocks)
+		}
+	}
+	t.pending = t.pending[:0]
+}
+
+func (t *CallTracer) BeforeInstruction(context *Context, address int, instruction Instruction) {
+	t.resolve(address, context.Clocks)
+}
+
+func (t *CallTracer) AfterInstruction(context *Context, address int, instruction Instruction) {
+	if context.InstructionFailed() {
+		return
+	}
+	switch {
+	case isCall(instruction.Type):
+		t.pending = append(t.pending, tracePending{action: traceEnterCall, address: context.InstructionAddress()})
+	case isReturn(instruction.Type):
+		t.pending = append(t.pending, tracePending{action: traceLeaveCall})
+	case instruction.Type == IT_InterruptReturn:
+		t.pending = append(t.pending, tracePending{action: traceLeaveInterrupt})
+	}
+}
+
+func (t *CallTracer) Interrupt(context *Context, number byte) {
+	if context.InterruptHandlers[number] != nil {
+		t.Events = append(t.Events, TraceEvent{
+			Name:      fmt.Sprintf("int 0x%02x", number),
+			Category:  "interrupt",
+			Phase:     "i",
+			Timestamp: context.Clocks,
+			ProcessID: 1,
+			ThreadID:  1,
+			Scope:     "t",
+		})
+		return
+	}
+	t.pending = append(t.pending, tracePending{action: traceEnterInterrupt, number: number})
+}
+
+// WriteJSON writes the trace, calls and interrupts that did not return yet end at the current clocks of the context
+func (t *CallTracer) WriteJSON(writer io.Writer, context *Context) error {
+	t.resolve(context.InstructionAddress(), context.Clocks)
+	events := append([]TraceEvent{}, t.Events...)
+	for i := len(t.stack) - 1; i >= 0; i-- {
+		event := t.stack[i].event
+		event.Duration = context.Clocks - event.Timestamp
+		events = append(events, event)
+	}
+
+	return json.NewEncoder(writer).Encode(struct {
+		TraceEvents []TraceEvent `json:"traceEvents"`
+	}{events})
+}
@@ -0,0 +1,69 @@
+package simulator8086
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallTracer(t *testing.T) {
+	context := &Context{}
+	loadCallProgram(context)
+	tracer := NewCallTracer(map[int]string{0x0a: "f"})
+	context.AddObserver(tracer)
+	Run(gocontext.Background(), context, RunOptions{})
+
+	call := func(name string, address string, timestamp uint64, duration uint64) TraceEvent {
+		return TraceEvent{Name: name, Category: "call", Phase: "X", Timestamp: timestamp, Duration: duration,
+			ProcessID: 1, ThreadID: 1, Args: map[string]string{"address": address}}
+	}
+	require.Equal(t, []TraceEvent{
+		call("sub_00011", "0x00011", 42, 12),
+		call("f", "0x0000a", 19, 43),
+		call("sub_00011", "0x00011", 104, 12),
+		call("f", "0x0000a", 81, 43),
+	}, tracer.Events)
+
+	output := &strings.Builder{}
+	require.NoError(t, tracer.WriteJSON(output, context))
+	trace := struct{ TraceEvents []map[string]any }{}
+	require.NoError(t, json.Unmarshal([]byte(output.String()), &trace))
+	require.Len(t, trace.TraceEvents, 4)
+	require.Equal(t, map[string]any{
+		"name": "f", "cat": "call", "ph": "X", "ts": 19.0, "dur": 43.0, "pid": 1.0, "tid": 1.0,
+		"args": map[string]any{"address": "0x0000a"},
+	}, trace.TraceEvents[1])
+}
+
+func TestCallTracerInterrupts(t *testing.T) {
+	context := &Context{}
+	copy(context.Memory[0x1000:], []byte{
+		0xcd, 0x40, // int 40h
+		0xcd, 0x21, // int 21h
+		0xf4, // hlt
+	})
+	copy(context.Memory[0x200:], []byte{
+		0xe8, 0x01, 0x00, // call $+4
+		0xcf, // iret
+		0xc3, // ret
+	})
+	context.WriteMemory16(0x40*4, 0x200)
+	context.InterruptHandlers[0x21] = func(context *Context, number byte) error { return nil }
+	context.InstructionPointer = 0x1000
+	context.SetRegister(SP, 0x800)
+
+	tracer := NewCallTracer(nil)
+	context.AddObserver(tracer)
+	Run(gocontext.Background(), context, RunOptions{})
+
+	require.Len(t, tracer.Events, 3)
+	require.Equal(t, "sub_00204", tracer.Events[0].Name)
+	require.Equal(t, uint64(51), tracer.Events[1].Timestamp)
+	require.Equal(t, "int 0x40", tracer.Events[1].Name)
+	require.Equal(t, "interrupt", tracer.Events[1].Category)
+	require.Equal(t, uint64(19+8+24), tracer.Events[1].Duration)
+	require.Equal(t, TraceEvent{Name: "int 0x21", Category: "interrupt", Phase: "i", Timestamp: 102, ProcessID: 1, ThreadID: 1, Scope: "t"}, tracer.Events[2])
+}
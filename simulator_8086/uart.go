@@ -0,0 +1,243 @@
+package simulator8086
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	COM1Port      = 0x3f8
+	COM1IRQ       = 4
+	uartPortCount = 8
+)
+
+// register offsets
+const (
+	uartData                = 0
+	uartInterruptEnable     = 1
+	uartInterruptIdentifier = 2
+	uartLineControl         = 3
+	uartModemControl        = 4
+	uartLineStatus          = 5
+	uartModemStatus         = 6
+	uartScratch             = 7
+)
+
+const (
+	uartEnableReceive  = 0x01
+	uartEnableTransmit = 0x02
+	uartEnableLine     = 0x04
+
+	uartDivisorLatchAccess = 0x80
+	// OUT2 connects the interrupt output to the PIC on the PC
+	uartOut2     = 0x08
+	uartLoopback = 0x10
+
+	uartDataReady    = 0x01
+	uartOverrun      = 0x02
+	uartTransmitIdle = 0x60
+
+	uartNoInterrupt       = 0x01
+	uartLineInterrupt     = 0x06
+	uartReceiveInterrupt  = 0x04
+	uartTransmitInterrupt = 0x02
+
+	// CTS, DSR and DCD are always active
+	uartModemLines = 0xb0
+)
+
+type uartState struct {
+	Receive         byte
+	InterruptEnable byte
+	LineControl     byte
+	ModemControl    byte
+	LineStatus      byte
+	Scratch         byte
+	Divisor         uint16
+	// set when the transmit holding register became empty and the interrupt was not identified yet
+	TransmitInterrupt bool
+}
+
+// UART is an 8250 serial port connected to a reader and a writer. Transmitted bytes are written
+// immediately, received bytes are read in the background and appear in the receive register
+// as soon as it is empty, so no data is lost while the program is not reading.
+// Interrupts are raised on IRQ of the PIC, if there is one, while OUT2 of the modem control register is set like on the PC.
+type UART struct {
+	PIC *PIC
+	IRQ int
+	// Err is the first error of the writer
+	Err error
+
+	writer   io.Writer
+	received chan byte
+	state    uartState
+}
+
+// NewUART starts reading from reader in the background, reader may be nil if nothing is received
+func NewUART(reader io.Reader, writer io.Writer) *UART {
+	u := &UART{IRQ: COM1IRQ, writer: writer, received: make(chan byte, 256)}
+	u.state.LineStatus = uartTransmitIdle
+	if reader != nil {
+		go u.receive(reader)
+	}
+	return u
+}
+
+func (u *UART) receive(reader io.Reader) {
+	buffer := make([]byte, 256)
+	for {
+		n, err := reader.Read(buffer)
+		for _, b := range buffer[:n] {
+			u.received <- b
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Attach maps the UART to the ports of COM1 and polls for received data while the simulation runs
+func (u *UART) Attach(context *Context) error {
+	context.ClockedDevices = append(context.ClockedDevices, u)
+	return context.attachPorts(COM1Port, uartPortCount, u)
+}
+
+func (u *UART) AdvanceClocks(clocks int) {
+	u.poll()
+}
+
+// poll moves the next received byte into the empty receive register
+func (u *UART) poll() {
+	if u.state.LineStatus&uartDataReady != 0 {
+		return
+	}
+	select {
+	case b := <-u.received:
+		u.state.Receive = b
+		u.state.LineStatus |= uartDataReady
+		u.updateInterrupt()
+	default:
+	}
+}
+
+func (u *UART) interruptIdentifier() byte {
+	enabled := u.state.InterruptEnable
+	switch {
+	case enabled&uartEnableLine != 0 && u.state.LineStatus&uartOverrun != 0:
+		return uartLineInterrupt
+	case enabled&uartEnableReceive != 0 && u.state.LineStatus&uartDataReady != 0:
+		return uartReceiveInterrupt
+	case enabled&uartEnableTransmit != 0 && u.state.TransmitInterrupt:
+		return uartTransmitInterrupt
+	}
+	return uartNoInterrupt
+}
+
+func (u *UART) updateInterrupt() {
+	if u.PIC == nil {
+		return
+	}
+	pending := u.interruptIdentifier() != uartNoInterrupt
+	u.PIC.SetIRQ(u.IRQ, pending && u.state.ModemControl&uartOut2 != 0)
+}
+
+func (u *UART) divisorLatchAccess() bool {
+	return u.state.LineControl&uartDivisorLatchAccess != 0
+}
+
+func (u *UART) ReadPort(offset int) byte {
+	defer u.updateInterrupt()
+
+	state := &u.state
+	switch offset {
+	case uartData:
+		if u.divisorLatchAccess() {
+			return byte(state.Divisor)
+		}
+		// the next byte arrives with the next poll, so the interrupt line drops in between
+		state.LineStatus &^= uartDataReady
+		return state.Receive
+	case uartInterruptEnable:
+		if u.divisorLatchAccess() {
+			return byte(state.Divisor >> 8)
+		}
+		return state.InterruptEnable
+	case uartInterruptIdentifier:
+		identifier := u.interruptIdentifier()
+		if identifier == uartTransmitInterrupt {
+			state.TransmitInterrupt = false
+		}
+		return identifier
+	case uartLineControl:
+		return state.LineControl
+	case uartModemControl:
+		return state.ModemControl
+	case uartLineStatus:
+		u.poll()
+		value := state.LineStatus
+		state.LineStatus &^= uartOverrun
+		return value
+	case uartModemStatus:
+		return uartModemLines
+	}
+	return state.Scratch
+}
+
+func (u *UART) WritePort(offset int, value byte) {
+	defer u.updateInterrupt()
+
+	state := &u.state
+	switch offset {
+	case uartData:
+		if u.divisorLatchAccess() {
+			state.Divisor = state.Divisor&0xff00 | uint16(value)
+			return
+		}
+		u.transmit(value)
+	case uartInterruptEnable:
+		if u.divisorLatchAccess() {
+			state.Divisor = state.Divisor&0x00ff | uint16(value)<<8
+			return
+		}
+		if value&uartEnableTransmit != 0 && state.InterruptEnable&uartEnableTransmit == 0 {
+			// enabling the interrupt while the transmitter is empty triggers it
+			state.TransmitInterrupt = true
+		}
+		state.InterruptEnable = value & 0x0f
+	case uartLineControl:
+		state.LineControl = value
+	case uartModemControl:
+		state.ModemControl = value & 0x1f
+	case uartScratch:
+		state.Scratch = value
+	}
+}
+
+func (u *UART) transmit(value byte) {
+	if u.state.ModemControl&uartLoopback != 0 {
+		if u.state.LineStatus&uartDataReady != 0 {
+			u.state.LineStatus |= uartOverrun
+		}
+		u.state.Receive = value
+		u.state.LineStatus |= uartDataReady
+	} else if u.writer != nil && u.Err == nil {
+		_, u.Err = u.writer.Write([]byte{value})
+	}
+	// the byte is sent instantly, so the transmitter is empty again right away
+	u.state.TransmitInterrupt = true
+}
+
+func (u *UART) DeviceName() string {
+	return "uart"
+}
+
+func (u *UART) SaveState() ([]byte, error) {
+	buffer := bytes.Buffer{}
+	err := binary.Write(&buffer, binary.LittleEndian, u.state)
+	return buffer.Bytes(), err
+}
+
+func (u *UART) LoadState(state []byte) error {
+	return binary.Read(bytes.NewReader(state), binary.LittleEndian, &u.state)
+}
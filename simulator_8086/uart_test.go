@@ -0,0 +1,81 @@
+package simulator8086
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUARTReceiveInterruptEcho(t *testing.T) {
+	context := &Context{}
+	pic := NewPIC()
+	require.NoError(t, pic.Attach(context))
+	output := &bytes.Buffer{}
+	uart := NewUART(strings.NewReader("hello"), output)
+	uart.PIC = pic
+	require.NoError(t, uart.Attach(context))
+
+	copy(context.Memory[0x1000:], []byte{
+		0xb0, 0x13, 0xe6, 0x20, // mov al, 13h; out 20h, al
+		0xb0, 0x08, 0xe6, 0x21, // mov al, 8; out 21h, al
+		0xb0, 0x01, 0xe6, 0x21, // mov al, 1; out 21h, al
+		0xb0, 0xef, 0xe6, 0x21, // mov al, 0efh; out 21h, al
+		0xba, 0xf9, 0x03, // mov dx, 3f9h
+		0xb0, 0x01, 0xee, // mov al, 1; out dx, al
+		0xba, 0xfc, 0x03, // mov dx, 3fch
+		0xb0, 0x08, 0xee, // mov al, 8; out dx, al
+		0xfb,       // sti
+		0xe3, 0xfe, // jcxz $
+	})
+	copy(context.Memory[0x2000:], []byte{
+		0xba, 0xf8, 0x03, // mov dx, 3f8h
+		0xec,                   // in al, dx
+		0xee,                   // out dx, al
+		0xb0, 0x20, 0xe6, 0x20, // mov al, 20h; out 20h, al
+		0xcf, // iret
+	})
+	context.WriteMemory16(0x0c*4, 0x2000)
+	context.InstructionPointer = 0x1000
+	context.SetRegister(SP, 0x0800)
+
+	for i := 0; i < 1000000 && output.Len() < 5; i++ {
+		_, err := Step(context)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "hello", output.String())
+	require.NoError(t, uart.Err)
+}
+
+func TestUARTRegisters(t *testing.T) {
+	output := &bytes.Buffer{}
+	uart := NewUART(nil, output)
+
+	require.Equal(t, byte(uartTransmitIdle), uart.ReadPort(uartLineStatus))
+	require.Equal(t, byte(uartNoInterrupt), uart.ReadPort(uartInterruptIdentifier))
+
+	// the divisor latch shares the data and interrupt enable registers
+	uart.WritePort(uartLineControl, 0x83)
+	uart.WritePort(uartData, 0x0c)
+	uart.WritePort(uartInterruptEnable, 0x00)
+	uart.WritePort(uartLineControl, 0x03)
+	require.Equal(t, uint16(12), uart.state.Divisor)
+	require.Equal(t, byte(0), uart.ReadPort(uartInterruptEnable))
+
+	uart.WritePort(uartData, 'A')
+	require.Equal(t, "A", output.String())
+
+	uart.WritePort(uartInterruptEnable, uartEnableTransmit)
+	require.Equal(t, byte(uartTransmitInterrupt), uart.ReadPort(uartInterruptIdentifier))
+	require.Equal(t, byte(uartNoInterrupt), uart.ReadPort(uartInterruptIdentifier))
+
+	// loopback
+	uart.WritePort(uartModemControl, uartLoopback)
+	uart.WritePort(uartData, 'x')
+	uart.WritePort(uartData, 'y')
+	require.Equal(t, byte(uartTransmitIdle|uartDataReady|uartOverrun), uart.ReadPort(uartLineStatus))
+	require.Equal(t, byte('y'), uart.ReadPort(uartData))
+	require.Equal(t, byte(uartTransmitIdle), uart.ReadPort(uartLineStatus))
+	require.Equal(t, "A", output.String())
+}